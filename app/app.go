@@ -0,0 +1,88 @@
+package app
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// EnvConfigParam returns environment variable of given key or default value
+// if variable is not set. Empty param is fatal.
+func EnvConfigParam(key, defaultVal string) string {
+	if key == "" {
+		logrus.Fatal("environment config param with empty key requested")
+	}
+
+	v := os.Getenv(key)
+	if v == "" {
+		v = defaultVal
+	}
+
+	if v == "" {
+		logrus.WithField("environment_variable", key).
+			Fatal("environment config param is empty")
+	}
+
+	return v
+}
+
+type closer struct {
+	name  string
+	close func() error
+}
+
+// App is common skeleton of camtester programs. Components added by
+// Closer are closed in reverse order when app is stopped or fails.
+type App struct {
+	name    string
+	closers []closer
+}
+
+func New(name string) *App {
+	logrus.SetLevel(logrus.DebugLevel)
+	return &App{name: name}
+}
+
+// Closer adds component of given name which is closed on app stop.
+func (a *App) Closer(name string, close func() error) {
+	a.closers = append(a.closers, closer{name: name, close: close})
+}
+
+// Fatal closes added components and exits with error.
+func (a *App) Fatal(err error, msg string) {
+	a.close()
+	logrus.WithError(err).Fatal(msg)
+}
+
+// Run waits for interrupt or termination signal and closes added
+// components.
+func (a *App) Run() {
+	logrus.Info(a.name + " started")
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	logrus.Infof("captured %v signal, stopping", <-signals)
+
+	st := time.Now()
+
+	a.close()
+
+	logrus.Infof("stopped in %s seconds, exiting", time.Now().Sub(st))
+}
+
+func (a *App) close() {
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		c := a.closers[i]
+		err := c.close()
+		if err != nil {
+			logrus.WithError(err).Error("failed to close " + c.name)
+		} else {
+			logrus.Info(c.name + " closed")
+		}
+	}
+	a.closers = nil
+}
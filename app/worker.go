@@ -0,0 +1,74 @@
+package app
+
+import (
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/dimuls/camtester/entity"
+	"github.com/dimuls/camtester/nats"
+	"github.com/dimuls/camtester/worker"
+)
+
+// Handler is task worker.
+type Handler interface {
+	HandleTask(t entity.Task) error
+	CancelTask(taskID string)
+}
+
+// RunWorker runs worker program which handles tasks of given type by
+// handler created by newHandler. Task results are published to nats.
+func RunWorker(name, taskType string,
+	newHandler func(trp worker.TaskResultPublisher) (Handler, error)) {
+
+	a := New(name)
+
+	natsURL := EnvConfigParam("NATS_URL", "")
+	natsClusterID := EnvConfigParam("NATS_CLUSTER_ID", "camtester")
+	natsClientID := EnvConfigParam("NATS_CLIENT_ID", "")
+	geoLocation := EnvConfigParam("GEO_LOCATION", "")
+	concurrencyStr := EnvConfigParam("CONCURRENCY", "100")
+
+	concurrency, err := strconv.Atoi(concurrencyStr)
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to parse concurrency")
+	}
+
+	logrus.Info("environment config params loaded")
+
+	trp, err := nats.NewTaskResultPublisher(natsURL, natsClusterID,
+		natsClientID)
+	if err != nil {
+		a.Fatal(err, "failed create nats task result publisher")
+	}
+	a.Closer("task result publisher", trp.Close)
+
+	logrus.Info("task result publisher created")
+
+	h, err := newHandler(trp)
+	if err != nil {
+		a.Fatal(err, "failed to create task handler")
+	}
+
+	logrus.Info("task handler created")
+
+	tc, err := nats.NewTaskConsumer(natsURL, natsClusterID, natsClientID,
+		geoLocation, taskType, concurrency, h)
+	if err != nil {
+		a.Fatal(err, "failed create new task consumer")
+	}
+	a.Closer("task consumer", tc.Close)
+
+	logrus.Info("task consumer created and started")
+
+	tcc, err := nats.NewTaskCancelConsumer(natsURL, natsClusterID,
+		natsClientID, h)
+	if err != nil {
+		a.Fatal(err, "failed create new task cancel consumer")
+	}
+	a.Closer("task cancel consumer", tcc.Close)
+
+	logrus.Info("task cancel consumer created and started")
+
+	a.Run()
+}
@@ -0,0 +1,119 @@
+package artifact
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+
+	"github.com/dimuls/camtester/entity"
+)
+
+// FileStore is artifact store which keeps artifacts as files in directory.
+// Content type is kept in file next to artifact file. If it's missing
+// content type is detected from artifact data.
+type FileStore struct {
+	dir string
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("create directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) Artifact(artifactID string) (a entity.Artifact,
+	err error) {
+
+	if !validID(artifactID) {
+		err = entity.ErrArtifactNotFound
+		return
+	}
+
+	path := filepath.Join(s.dir, artifactID)
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = entity.ErrArtifactNotFound
+			return
+		}
+		err = fmt.Errorf("read file: %w", err)
+		return
+	}
+
+	contentType, err := ioutil.ReadFile(contentTypePath(path))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		err = fmt.Errorf("read content type file: %w", err)
+		return
+	}
+
+	if len(contentType) == 0 {
+		contentType = []byte(http.DetectContentType(data))
+	}
+
+	return entity.Artifact{
+		ID:          artifactID,
+		ContentType: string(contentType),
+		Data:        data,
+	}, nil
+}
+
+func (s *FileStore) SetArtifact(a entity.Artifact) error {
+	if !validID(a.ID) {
+		return errors.New("invalid artifact ID")
+	}
+
+	path := filepath.Join(s.dir, a.ID)
+
+	// Content type is written before data, so artifact is never served
+	// with content type of previous artifact data.
+	err := writeFile(contentTypePath(path), []byte(a.ContentType))
+	if err != nil {
+		return fmt.Errorf("write content type file: %w", err)
+	}
+
+	err = writeFile(path, a.Data)
+	if err != nil {
+		return fmt.Errorf("write artifact file: %w", err)
+	}
+
+	return nil
+}
+
+// writeFile writes data to temporary file first and then renames it, so
+// partially written file is never read.
+func writeFile(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+
+	err := ioutil.WriteFile(tmpPath, data, 0644)
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("write: %w", err)
+	}
+
+	err = os.Rename(tmpPath, path)
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("rename: %w", err)
+	}
+
+	return nil
+}
+
+func contentTypePath(path string) string {
+	return path + ".content-type"
+}
+
+// validID reports whether artifact ID is UUID. Other IDs are rejected, so
+// artifact ID can't be used to access files outside of store.
+func validID(artifactID string) bool {
+	_, err := uuid.Parse(artifactID)
+	return err == nil
+}
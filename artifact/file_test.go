@@ -0,0 +1,47 @@
+package artifact
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestFileStore(t *testing.T) {
+	s, err := NewFileStore(filepath.Join(t.TempDir(), "artifacts"))
+	if err != nil {
+		t.Fatalf("failed to create file store: %v", err)
+	}
+
+	testStore(t, s)
+}
+
+func TestFileStoreDetectContentType(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("failed to create file store: %v", err)
+	}
+
+	// Artifact without content type file, like artifact stored by previous
+	// version of store.
+	id := uuid.New().String()
+
+	err = ioutil.WriteFile(filepath.Join(dir, id),
+		[]byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00"), 0644)
+	if err != nil {
+		t.Fatalf("failed to write artifact file: %v", err)
+	}
+
+	a, err := s.Artifact(id)
+	if err != nil {
+		t.Fatalf("failed to get artifact: %v", err)
+	}
+
+	if a.ContentType != "image/jpeg" {
+		t.Errorf("expected detected content type image/jpeg, got %s",
+			a.ContentType)
+	}
+}
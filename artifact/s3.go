@@ -0,0 +1,116 @@
+package artifact
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/dimuls/camtester/entity"
+)
+
+// s3Timeout is timeout of every S3 request.
+const s3Timeout = 30 * time.Second
+
+// S3Store is artifact store which keeps artifacts as objects in bucket of
+// S3-compatible storage, for example MinIO.
+type S3Store struct {
+	client *minio.Client
+	bucket string
+}
+
+// NewS3Store connects to S3-compatible storage and creates bucket if it
+// doesn't exist.
+func NewS3Store(endpoint, accessKeyID, secretAccessKey, bucket string,
+	secure bool) (*S3Store, error) {
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKeyID, secretAccessKey, ""),
+		Secure: secure,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create client: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s3Timeout)
+	defer cancel()
+
+	exists, err := client.BucketExists(ctx, bucket)
+	if err != nil {
+		return nil, fmt.Errorf("check bucket existence: %w", err)
+	}
+
+	if !exists {
+		err = client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("make bucket: %w", err)
+		}
+	}
+
+	return &S3Store{client: client, bucket: bucket}, nil
+}
+
+func (s *S3Store) Artifact(artifactID string) (a entity.Artifact,
+	err error) {
+
+	if !validID(artifactID) {
+		err = entity.ErrArtifactNotFound
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s3Timeout)
+	defer cancel()
+
+	o, err := s.client.GetObject(ctx, s.bucket, artifactID,
+		minio.GetObjectOptions{})
+	if err != nil {
+		err = fmt.Errorf("get object: %w", err)
+		return
+	}
+	defer o.Close()
+
+	info, err := o.Stat()
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			err = entity.ErrArtifactNotFound
+			return
+		}
+		err = fmt.Errorf("stat object: %w", err)
+		return
+	}
+
+	data, err := ioutil.ReadAll(o)
+	if err != nil {
+		err = fmt.Errorf("read object: %w", err)
+		return
+	}
+
+	return entity.Artifact{
+		ID:          artifactID,
+		ContentType: info.ContentType,
+		Data:        data,
+	}, nil
+}
+
+func (s *S3Store) SetArtifact(a entity.Artifact) error {
+	if !validID(a.ID) {
+		return errors.New("invalid artifact ID")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s3Timeout)
+	defer cancel()
+
+	_, err := s.client.PutObject(ctx, s.bucket, a.ID,
+		bytes.NewReader(a.Data), int64(len(a.Data)),
+		minio.PutObjectOptions{ContentType: a.ContentType})
+	if err != nil {
+		return fmt.Errorf("put object: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+//go:build minio
+// +build minio
+
+package artifact
+
+import (
+	"os"
+	"testing"
+)
+
+// TestS3Store runs against real S3-compatible storage, for example MinIO
+// started by "docker run -p 9000:9000 minio/minio server /data":
+//
+//	S3_ENDPOINT=localhost:9000 S3_ACCESS_KEY_ID=minioadmin \
+//	S3_SECRET_ACCESS_KEY=minioadmin go test -tags minio ./artifact/
+func TestS3Store(t *testing.T) {
+	endpoint := os.Getenv("S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("S3_ENDPOINT is not set")
+	}
+
+	s, err := NewS3Store(endpoint, os.Getenv("S3_ACCESS_KEY_ID"),
+		os.Getenv("S3_SECRET_ACCESS_KEY"), "camtester-artifacts-test", false)
+	if err != nil {
+		t.Fatalf("failed to create S3 store: %v", err)
+	}
+
+	testStore(t, s)
+}
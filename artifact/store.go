@@ -0,0 +1,49 @@
+package artifact
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/dimuls/camtester/entity"
+)
+
+// Store is artifact store.
+type Store interface {
+	Artifact(artifactID string) (entity.Artifact, error)
+	SetArtifact(entity.Artifact) error
+}
+
+// ConfigParam returns config param of given key or default value if param
+// is not set.
+type ConfigParam func(key, defaultVal string) string
+
+// NewStore creates artifact store configured by given config params:
+// objects in S3-compatible storage, which is default, or files in local
+// directory. File store can be shared by components only if they are on
+// same host or directory is shared volume, so it's used only if it's
+// explicitly set.
+func NewStore(param ConfigParam) (Store, error) {
+	switch kind := param("ARTIFACT_STORE", "s3"); kind {
+	case "file":
+		return NewFileStore(param("ARTIFACT_DIR",
+			"/var/lib/camtester/artifacts"))
+	case "s3":
+		endpoint := param("S3_ENDPOINT", "")
+		if endpoint == "" {
+			return nil, errors.New("S3 endpoint is not set")
+		}
+		secure, err := strconv.ParseBool(param("S3_SECURE", "false"))
+		if err != nil {
+			return nil, fmt.Errorf("parse S3 secure: %w", err)
+		}
+		return NewS3Store(
+			endpoint,
+			param("S3_ACCESS_KEY_ID", ""),
+			param("S3_SECRET_ACCESS_KEY", ""),
+			param("S3_BUCKET", "camtester-artifacts"),
+			secure)
+	default:
+		return nil, fmt.Errorf("unknown artifact store %s", kind)
+	}
+}
@@ -0,0 +1,129 @@
+package artifact
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/dimuls/camtester/entity"
+)
+
+// testStore checks store behaviour common for all stores.
+func testStore(t *testing.T, s Store) {
+	t.Run("set and get", func(t *testing.T) {
+		a := entity.Artifact{
+			ID:          uuid.New().String(),
+			ContentType: "image/jpeg",
+			Data:        []byte("not really jpeg"),
+		}
+
+		err := s.SetArtifact(a)
+		if err != nil {
+			t.Fatalf("failed to set artifact: %v", err)
+		}
+
+		ga, err := s.Artifact(a.ID)
+		if err != nil {
+			t.Fatalf("failed to get artifact: %v", err)
+		}
+
+		if ga.ID != a.ID {
+			t.Errorf("expected ID %s, got %s", a.ID, ga.ID)
+		}
+		if ga.ContentType != a.ContentType {
+			t.Errorf("expected content type %s, got %s", a.ContentType,
+				ga.ContentType)
+		}
+		if !bytes.Equal(ga.Data, a.Data) {
+			t.Errorf("expected data %q, got %q", a.Data, ga.Data)
+		}
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		a := entity.Artifact{
+			ID:          uuid.New().String(),
+			ContentType: "image/jpeg",
+			Data:        []byte("first"),
+		}
+
+		err := s.SetArtifact(a)
+		if err != nil {
+			t.Fatalf("failed to set artifact: %v", err)
+		}
+
+		a.ContentType = "image/png"
+		a.Data = []byte("second")
+
+		err = s.SetArtifact(a)
+		if err != nil {
+			t.Fatalf("failed to set artifact: %v", err)
+		}
+
+		ga, err := s.Artifact(a.ID)
+		if err != nil {
+			t.Fatalf("failed to get artifact: %v", err)
+		}
+
+		if ga.ContentType != a.ContentType || !bytes.Equal(ga.Data, a.Data) {
+			t.Errorf("expected %s %q, got %s %q", a.ContentType, a.Data,
+				ga.ContentType, ga.Data)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := s.Artifact(uuid.New().String())
+		if !errors.Is(err, entity.ErrArtifactNotFound) {
+			t.Errorf("expected artifact not found error, got %v", err)
+		}
+	})
+
+	t.Run("invalid ID", func(t *testing.T) {
+		for _, id := range []string{"", "../artifact", "not-uuid"} {
+			_, err := s.Artifact(id)
+			if !errors.Is(err, entity.ErrArtifactNotFound) {
+				t.Errorf("expected artifact not found error for ID %q, got %v",
+					id, err)
+			}
+
+			err = s.SetArtifact(entity.Artifact{ID: id, Data: []byte("data")})
+			if err == nil {
+				t.Errorf("expected error for ID %q", id)
+			}
+		}
+	})
+}
+
+func TestNewStore(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name   string
+		params map[string]string
+		ok     bool
+	}{
+		{"default without S3 endpoint", nil, false},
+		{"S3 without endpoint", map[string]string{"ARTIFACT_STORE": "s3"}, false},
+		{"file", map[string]string{"ARTIFACT_STORE": "file",
+			"ARTIFACT_DIR": dir}, true},
+		{"unknown", map[string]string{"ARTIFACT_STORE": "ftp"}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewStore(func(key, defaultVal string) string {
+				if v, ok := test.params[key]; ok {
+					return v
+				}
+				return defaultVal
+			})
+			if test.ok && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if !test.ok && err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
@@ -1,22 +1,21 @@
 package checker
 
 import (
-	"encoding/base64"
-	"fmt"
+	"context"
+	"errors"
 	"math"
-	"time"
 
 	"github.com/gonum/stat"
 	"github.com/sirupsen/logrus"
 
 	"github.com/dimuls/camtester/entity"
 	"github.com/dimuls/camtester/ffmpeg"
+	"github.com/dimuls/camtester/worker"
 )
 
 const TaskType = "check"
 
 const sampleContainerExt = "mkv"
-const sampleDurationSec = 10
 
 type ProbeResult struct {
 	SampleDurationSec     int `json:"sample_duration_sec"`
@@ -38,74 +37,48 @@ type TaskResultPublisher interface {
 }
 
 type Checker struct {
-	ffmpegPath          string
-	restreamerProvider  RestreamerProvider
-	taskResultPublisher TaskResultPublisher
-	log                 *logrus.Entry
+	ffmpegPath         string
+	restreamerProvider RestreamerProvider
+
+	*worker.Base
 }
 
 func NewChecker(rp RestreamerProvider, trp TaskResultPublisher,
 	ffmpegPath string) *Checker {
 	return &Checker{
-		ffmpegPath:          ffmpegPath,
-		restreamerProvider:  rp,
-		taskResultPublisher: trp,
-		log:                 logrus.WithField("subsystem", "checker"),
+		ffmpegPath:         ffmpegPath,
+		restreamerProvider: rp,
+		Base:               worker.NewBase(trp, logrus.WithField("subsystem", "checker")),
 	}
 }
 
 func (c *Checker) HandleTask(t entity.Task) error {
-	log := c.log.WithField("task_id", t.ID)
-
-	log.Debug("task received")
-
-	var uri string
-
-	tr := entity.TaskResult{TaskID: t.ID}
-
-	err := t.UnmarshalPayload(&uri)
-	if err != nil {
-		errMsg := "failed to unmarshal task payload"
-		log.WithError(err).WithField("payload", string(t.Payload)).
-			Error(errMsg)
-		return c.handleError(tr, errMsg, err)
-	}
+	var p Payload
 
-	restreamerAddr, err := c.restreamerProvider.ProvideRestreamer(uri)
-	if err != nil {
-		errMsg := "failed to get restreamer host"
-		log.WithError(err).Error(errMsg)
-		return c.handleError(tr, errMsg, err)
+	wt, ok, err := c.StartStreamTask(t, &p, c.restreamerProvider)
+	if !ok {
+		return err
 	}
+	defer wt.Done()
 
-	log = log.WithField("restreamer_host", restreamerAddr)
-
-	log.Debug("got restreamer host")
-
-	origURIBase64 := base64.StdEncoding.EncodeToString([]byte(uri))
+	ctx, log, tr := wt.Ctx, wt.Log, wt.Result
 
-	uri = fmt.Sprintf("rtsp://%s/%s", restreamerAddr, origURIBase64)
-
-	ch, err := ffmpeg.CheckStream(c.ffmpegPath, uri, sampleDurationSec)
+	ch, err := ffmpeg.CheckStream(ctx, c.ffmpegPath, wt.StreamURI,
+		p.Transport, p.sampleDurationSec())
 	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			log.Info("task cancelled")
+			return nil
+		}
 		errMsg := "failed to check stream"
 		log.WithError(err).Error(errMsg)
-		return c.handleError(tr, errMsg, err)
-	}
-
-	tr.Ok = true
-	tr.Time = time.Now()
-
-	err = tr.MarshalPayload(ch)
-	if err != nil {
-		log.WithError(err).Error("failed to marshal task result payload")
-		return fmt.Errorf("marshal tast result payload: %w", err)
+		return c.PublishError(tr, ffmpeg.ErrorCode(err),
+			entity.ErrorStageCheck, errMsg, err)
 	}
 
-	err = c.taskResultPublisher.PublishTaskResult(tr)
+	err = c.PublishResult(tr, ch)
 	if err != nil {
-		log.WithError(err).Error("failed to publish task result")
-		return fmt.Errorf("publish task result: %w", err)
+		return err
 	}
 
 	log.Debug("task successfully handled")
@@ -113,26 +86,6 @@ func (c *Checker) HandleTask(t entity.Task) error {
 	return nil
 }
 
-func (c *Checker) handleError(tr entity.TaskResult,
-	errMsg string, err error) error {
-
-	tr.Time = time.Now()
-
-	err = tr.MarshalPayload(errMsg + ": " + err.Error())
-	if err != nil {
-		c.log.WithError(err).Error("failed to marshal task result payload")
-		return fmt.Errorf("marshal task result payload: %w", err)
-	}
-
-	err = c.taskResultPublisher.PublishTaskResult(tr)
-	if err != nil {
-		c.log.WithError(err).Error("failed to publish task result")
-		return fmt.Errorf("publish task result: %w", err)
-	}
-
-	return nil
-}
-
 func zScore(samples []float64, lag int, threshold, influence float64) (signals []int) {
 	signals = make([]int, len(samples))
 	filteredY := make([]float64, len(samples))
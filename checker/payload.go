@@ -0,0 +1,72 @@
+package checker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/dimuls/camtester/worker"
+)
+
+const (
+	defaultSampleDurationSec = 10
+	maxSampleDurationSec     = 60
+)
+
+// Payload is check task payload. It can be plain string stream URI too.
+type Payload struct {
+	URI         string `json:"uri"`
+	DurationSec int    `json:"duration_sec,omitempty"`
+	Transport   string `json:"transport,omitempty"`
+}
+
+func (p *Payload) UnmarshalJSON(data []byte) error {
+	var uri string
+
+	if json.Unmarshal(data, &uri) == nil {
+		*p = Payload{URI: uri}
+		return nil
+	}
+
+	type payload Payload
+
+	var pp payload
+
+	err := json.Unmarshal(data, &pp)
+	if err != nil {
+		return err
+	}
+
+	*p = Payload(pp)
+
+	return nil
+}
+
+func (p Payload) StreamURI() string {
+	return p.URI
+}
+
+func (p Payload) Validate() error {
+	if p.URI == "" {
+		return errors.New("uri is empty")
+	}
+
+	if p.DurationSec < 0 || p.DurationSec > maxSampleDurationSec {
+		return fmt.Errorf("duration_sec is not in [0, %d]",
+			maxSampleDurationSec)
+	}
+
+	err := worker.ValidateTransport(p.Transport)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (p Payload) sampleDurationSec() int {
+	if p.DurationSec == 0 {
+		return defaultSampleDurationSec
+	}
+	return p.DurationSec
+}
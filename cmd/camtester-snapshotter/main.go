@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/dimuls/camtester/app"
+	"github.com/dimuls/camtester/artifact"
+	"github.com/dimuls/camtester/http"
+	"github.com/dimuls/camtester/snapshot"
+	"github.com/dimuls/camtester/worker"
+)
+
+func main() {
+	ffmpegPath := app.EnvConfigParam("FFMPEG_PATH", "/usr/bin/ffmpeg")
+	restreamerProviderURI := app.EnvConfigParam("RESTREAMER_PROVIDER_URI", "")
+	artifactURLPref := app.EnvConfigParam("ARTIFACT_URL_PREFIX", "/artifacts/")
+
+	app.RunWorker("camtester-snapshotter", snapshot.TaskType,
+		func(trp worker.TaskResultPublisher) (app.Handler, error) {
+			as, err := artifact.NewStore(app.EnvConfigParam)
+			if err != nil {
+				return nil, fmt.Errorf("create artifact store: %w", err)
+			}
+			return snapshot.NewSnapshotter(
+				http.NewRestreamerProvider(restreamerProviderURI), trp, as,
+				ffmpegPath, artifactURLPref), nil
+		})
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/dimuls/camtester/app"
+	"github.com/dimuls/camtester/artifact"
+	"github.com/dimuls/camtester/http"
+	"github.com/dimuls/camtester/tamper"
+	"github.com/dimuls/camtester/worker"
+)
+
+func main() {
+	ffmpegPath := app.EnvConfigParam("FFMPEG_PATH", "/usr/bin/ffmpeg")
+	restreamerProviderURI := app.EnvConfigParam("RESTREAMER_PROVIDER_URI", "")
+
+	app.RunWorker("camtester-tamper", tamper.TaskType,
+		func(trp worker.TaskResultPublisher) (app.Handler, error) {
+			as, err := artifact.NewStore(app.EnvConfigParam)
+			if err != nil {
+				return nil, fmt.Errorf("create artifact store: %w", err)
+			}
+			return tamper.NewDetector(
+				http.NewRestreamerProvider(restreamerProviderURI), trp, as,
+				ffmpegPath), nil
+		})
+}
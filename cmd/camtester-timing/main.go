@@ -0,0 +1,20 @@
+package main
+
+import (
+	"github.com/dimuls/camtester/app"
+	"github.com/dimuls/camtester/http"
+	"github.com/dimuls/camtester/timing"
+	"github.com/dimuls/camtester/worker"
+)
+
+func main() {
+	ffprobePath := app.EnvConfigParam("FFPROBE_PATH", "/usr/bin/ffprobe")
+	restreamerProviderURI := app.EnvConfigParam("RESTREAMER_PROVIDER_URI", "")
+
+	app.RunWorker("camtester-timing", timing.TaskType,
+		func(trp worker.TaskResultPublisher) (app.Handler, error) {
+			return timing.NewAnalyzer(
+				http.NewRestreamerProvider(restreamerProviderURI), trp,
+				ffprobePath), nil
+		})
+}
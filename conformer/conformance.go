@@ -0,0 +1,167 @@
+package conformer
+
+import (
+	"errors"
+	"math"
+	"strings"
+
+	"github.com/dimuls/camtester/entity"
+	"github.com/dimuls/camtester/ffmpeg"
+)
+
+// Conformance parameters.
+const (
+	ParameterCodec            = "codec"
+	ParameterProfile          = "profile"
+	ParameterWidth            = "width"
+	ParameterHeight           = "height"
+	ParameterFrameRate        = "frame_rate"
+	ParameterBitrate          = "bitrate_kbps"
+	ParameterKeyframeInterval = "keyframe_interval_sec"
+)
+
+type ConformanceResult struct {
+	SampleDurationSec int                  `json:"sample_duration_sec"`
+	Measured          entity.StreamProfile `json:"measured"`
+	Deviations        []Deviation          `json:"deviations"`
+	Conforms          bool                 `json:"conforms"`
+}
+
+// Deviation is measured parameter value which differs from expected one.
+// Actual is nil if parameter can't be measured in sample.
+type Deviation struct {
+	Parameter string      `json:"parameter"`
+	Expected  interface{} `json:"expected"`
+	Actual    interface{} `json:"actual"`
+}
+
+// measurer accumulates video packets statistics.
+type measurer struct {
+	packets      int
+	bytes        int
+	minTs, maxTs float64
+	keyframeTs   []float64
+}
+
+func (m *measurer) add(p ffmpeg.Packet) error {
+	ts := p.DTSTime
+	if ts == nil {
+		ts = p.PTSTime
+	}
+	if ts == nil {
+		return nil
+	}
+
+	if m.packets == 0 || *ts < m.minTs {
+		m.minTs = *ts
+	}
+	if m.packets == 0 || *ts > m.maxTs {
+		m.maxTs = *ts
+	}
+
+	m.packets++
+	m.bytes += p.Size
+
+	if p.Keyframe {
+		m.keyframeTs = append(m.keyframeTs, *ts)
+	}
+
+	return nil
+}
+
+// keyframes returns count of keyframes in sample.
+func (m *measurer) keyframes() int {
+	return len(m.keyframeTs)
+}
+
+func (m *measurer) profile(s ffmpeg.Stream) (p entity.StreamProfile,
+	err error) {
+
+	p.Codec = s.CodecName
+	p.Profile = s.Profile
+	p.Width = s.Width
+	p.Height = s.Height
+
+	span := m.maxTs - m.minTs
+
+	if m.packets < 2 || span <= 0 {
+		return p, errors.New("not enough video packets with timestamps")
+	}
+
+	// Sample duration includes duration of last frame.
+	duration := span * float64(m.packets) / float64(m.packets-1)
+
+	p.FrameRate = round(float64(m.packets-1)/span, 2)
+	p.BitrateKbps = int(math.Round(float64(m.bytes) * 8 / duration / 1000))
+
+	if len(m.keyframeTs) > 1 {
+		first, last := m.keyframeTs[0], m.keyframeTs[len(m.keyframeTs)-1]
+		p.KeyframeIntervalSec = round(
+			(last-first)/float64(len(m.keyframeTs)-1), 2)
+	}
+
+	return p, nil
+}
+
+func round(f float64, digits int) float64 {
+	m := math.Pow10(digits)
+	return math.Round(f*m) / m
+}
+
+func outOfTolerance(expected, actual, tolerance float64) bool {
+	return math.Abs(actual-expected) > expected*tolerance
+}
+
+// deviations compares measured profile with expected one. Keyframe interval
+// is not measured if sample has less than two keyframes.
+func deviations(expected, measured entity.StreamProfile, keyframes int,
+	t Tolerances) (ds []Deviation) {
+
+	if expected.Codec != "" &&
+		!strings.EqualFold(expected.Codec, measured.Codec) {
+		ds = append(ds, Deviation{ParameterCodec,
+			expected.Codec, measured.Codec})
+	}
+
+	if expected.Profile != "" &&
+		!strings.EqualFold(expected.Profile, measured.Profile) {
+		ds = append(ds, Deviation{ParameterProfile,
+			expected.Profile, measured.Profile})
+	}
+
+	if expected.Width != 0 && expected.Width != measured.Width {
+		ds = append(ds, Deviation{ParameterWidth,
+			expected.Width, measured.Width})
+	}
+
+	if expected.Height != 0 && expected.Height != measured.Height {
+		ds = append(ds, Deviation{ParameterHeight,
+			expected.Height, measured.Height})
+	}
+
+	if expected.FrameRate != 0 && outOfTolerance(expected.FrameRate,
+		measured.FrameRate, t.FrameRate) {
+		ds = append(ds, Deviation{ParameterFrameRate,
+			expected.FrameRate, measured.FrameRate})
+	}
+
+	if expected.BitrateKbps != 0 && outOfTolerance(
+		float64(expected.BitrateKbps), float64(measured.BitrateKbps),
+		t.Bitrate) {
+		ds = append(ds, Deviation{ParameterBitrate,
+			expected.BitrateKbps, measured.BitrateKbps})
+	}
+
+	if expected.KeyframeIntervalSec != 0 {
+		if keyframes < 2 {
+			ds = append(ds, Deviation{ParameterKeyframeInterval,
+				expected.KeyframeIntervalSec, nil})
+		} else if outOfTolerance(expected.KeyframeIntervalSec,
+			measured.KeyframeIntervalSec, t.KeyframeInterval) {
+			ds = append(ds, Deviation{ParameterKeyframeInterval,
+				expected.KeyframeIntervalSec, measured.KeyframeIntervalSec})
+		}
+	}
+
+	return
+}
@@ -0,0 +1,99 @@
+package conformer
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/dimuls/camtester/entity"
+	"github.com/dimuls/camtester/ffmpeg"
+	"github.com/dimuls/camtester/worker"
+)
+
+const TaskType = "conformance"
+
+type RestreamerProvider interface {
+	ProvideRestreamer(uri string) (string, error)
+}
+
+type TaskResultPublisher interface {
+	PublishTaskResult(entity.TaskResult) error
+}
+
+type Conformer struct {
+	ffprobePath        string
+	restreamerProvider RestreamerProvider
+
+	*worker.Base
+}
+
+func NewConformer(rp RestreamerProvider, trp TaskResultPublisher,
+	ffprobePath string) *Conformer {
+	return &Conformer{
+		ffprobePath:        ffprobePath,
+		restreamerProvider: rp,
+		Base:               worker.NewBase(trp, logrus.WithField("subsystem", "conformer")),
+	}
+}
+
+func (cf *Conformer) HandleTask(t entity.Task) error {
+	var p Payload
+
+	wt, ok, err := cf.StartStreamTask(t, &p, cf.restreamerProvider)
+	if !ok {
+		return err
+	}
+	defer wt.Done()
+
+	ctx, log, tr := wt.Ctx, wt.Log, wt.Result
+
+	var m measurer
+
+	streams, err := ffmpeg.ProbePackets(ctx, cf.ffprobePath, wt.StreamURI,
+		p.Transport, p.sampleDurationSec(), "v:0", m.add)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			log.Info("task cancelled")
+			return nil
+		}
+		errMsg := "failed to probe packets"
+		log.WithError(err).Error(errMsg)
+		return cf.PublishError(tr, ffmpeg.ErrorCode(err),
+			entity.ErrorStageProbe, errMsg, err)
+	}
+
+	if len(streams) == 0 {
+		errMsg := "failed to find video stream"
+		err = errors.New("no video stream")
+		log.Error(errMsg)
+		return cf.PublishError(tr, entity.ErrorCodeStreamNotFound,
+			entity.ErrorStageProbe, errMsg, err)
+	}
+
+	measured, err := m.profile(streams[0])
+	if err != nil {
+		errMsg := "failed to measure stream profile"
+		log.WithError(err).Error(errMsg)
+		return cf.PublishError(tr, entity.ErrorCodeDecodeFailed,
+			entity.ErrorStageProbe, errMsg, err)
+	}
+
+	cr := ConformanceResult{
+		SampleDurationSec: p.sampleDurationSec(),
+		Measured:          measured,
+		Deviations: deviations(p.Profile, measured, m.keyframes(),
+			p.Tolerances.withDefaults()),
+	}
+
+	cr.Conforms = len(cr.Deviations) == 0
+
+	err = cf.PublishResult(tr, cr)
+	if err != nil {
+		return err
+	}
+
+	log.Debug("task successfully handled")
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package conformer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dimuls/camtester/entity"
+	"github.com/dimuls/camtester/worker"
+)
+
+const (
+	defaultSampleDurationSec = 20
+	maxSampleDurationSec     = 60
+
+	defaultFrameRateTolerance        = 0.1
+	defaultBitrateTolerance          = 0.25
+	defaultKeyframeIntervalTolerance = 0.25
+)
+
+// Payload is conformance task payload. Zero fields of expected profile are
+// not checked.
+type Payload struct {
+	URI         string `json:"uri"`
+	DurationSec int    `json:"duration_sec,omitempty"`
+	Transport   string `json:"transport,omitempty"`
+
+	Profile    entity.StreamProfile `json:"profile"`
+	Tolerances Tolerances           `json:"tolerances,omitempty"`
+}
+
+// Tolerances are allowed relative deviations from expected profile.
+type Tolerances struct {
+	FrameRate        float64 `json:"frame_rate,omitempty"`
+	Bitrate          float64 `json:"bitrate,omitempty"`
+	KeyframeInterval float64 `json:"keyframe_interval,omitempty"`
+}
+
+func (t Tolerances) withDefaults() Tolerances {
+	if t.FrameRate == 0 {
+		t.FrameRate = defaultFrameRateTolerance
+	}
+	if t.Bitrate == 0 {
+		t.Bitrate = defaultBitrateTolerance
+	}
+	if t.KeyframeInterval == 0 {
+		t.KeyframeInterval = defaultKeyframeIntervalTolerance
+	}
+	return t
+}
+
+func (p Payload) StreamURI() string {
+	return p.URI
+}
+
+func (p Payload) Validate() error {
+	if p.URI == "" {
+		return errors.New("uri is empty")
+	}
+
+	if p.DurationSec < 0 || p.DurationSec > maxSampleDurationSec {
+		return fmt.Errorf("duration_sec is not in [0, %d]",
+			maxSampleDurationSec)
+	}
+
+	err := worker.ValidateTransport(p.Transport)
+	if err != nil {
+		return err
+	}
+
+	if p.Profile == (entity.StreamProfile{}) {
+		return errors.New("profile is empty")
+	}
+
+	if p.Profile.Width < 0 || p.Profile.Height < 0 ||
+		p.Profile.FrameRate < 0 || p.Profile.BitrateKbps < 0 ||
+		p.Profile.KeyframeIntervalSec < 0 {
+		return errors.New("profile has negative value")
+	}
+
+	if p.Tolerances.FrameRate < 0 || p.Tolerances.Bitrate < 0 ||
+		p.Tolerances.KeyframeInterval < 0 {
+		return errors.New("tolerance is negative")
+	}
+
+	return nil
+}
+
+func (p Payload) sampleDurationSec() int {
+	if p.DurationSec == 0 {
+		return defaultSampleDurationSec
+	}
+	return p.DurationSec
+}
@@ -0,0 +1,29 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo"
+
+	"github.com/dimuls/camtester/entity"
+)
+
+func (cr *Core) getArtifact(c echo.Context) error {
+	a, err := cr.artifacts.Artifact(c.Param("artifact-id"))
+	if err != nil {
+		if errors.Is(err, entity.ErrArtifactNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound,
+				"artifact not found")
+		}
+		return fmt.Errorf("get artifact from artifact store: %w", err)
+	}
+
+	contentType := a.ContentType
+	if contentType == "" {
+		contentType = http.DetectContentType(a.Data)
+	}
+
+	return c.Blob(http.StatusOK, contentType, a.Data)
+}
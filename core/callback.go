@@ -0,0 +1,155 @@
+package core
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo"
+
+	"github.com/dimuls/camtester/entity"
+)
+
+const (
+	callbackTimeout        = 10 * time.Second
+	callbackMaxAttempts    = 5
+	callbackInitialBackoff = time.Second
+
+	callbackSignatureHeader = "X-Camtester-Signature"
+	callbackTaskIDHeader    = "X-Camtester-Task-ID"
+)
+
+func hideCallbackSecret(t entity.Task) entity.Task {
+	if t.Callback != nil && t.Callback.Secret != "" {
+		cb := *t.Callback
+		cb.Secret = ""
+		t.Callback = &cb
+	}
+	return t
+}
+
+func signCallbackBody(secret string, body []byte) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write(body)
+	return "sha256=" + hex.EncodeToString(m.Sum(nil))
+}
+
+// deliverCallback posts finished task to its callback URL in background,
+// retrying with exponential backoff. Every attempt is logged in DB storage.
+func (cr *Core) deliverCallback(t entity.Task) {
+	log := cr.log.WithField("task_id", t.ID)
+
+	secret := t.Callback.Secret
+
+	body, err := json.Marshal(hideCallbackSecret(t))
+	if err != nil {
+		log.WithError(err).Error("failed to JSON marshal callback body")
+		return
+	}
+
+	cr.wg.Add(1)
+	go func() {
+		defer cr.wg.Done()
+
+		backoff := callbackInitialBackoff
+
+		for attempt := 1; attempt <= callbackMaxAttempts; attempt++ {
+			d := cr.postCallback(t.ID, t.Callback.URL, secret, body)
+			d.Attempt = attempt
+
+			err := cr.dbs.AddCallbackDelivery(t.ID, d)
+			if err != nil {
+				log.WithError(err).Error(
+					"failed to add callback delivery to DB storage")
+			}
+
+			if d.Ok {
+				log.Debug("callback delivered")
+				return
+			}
+
+			log.WithField("attempt", attempt).WithField("error", d.Error).
+				Warn("failed to deliver callback")
+
+			if attempt == callbackMaxAttempts {
+				break
+			}
+
+			select {
+			case <-cr.stop:
+				return
+			case <-time.After(backoff):
+			}
+
+			backoff *= 2
+		}
+
+		log.Error("callback delivery attempts exhausted")
+	}()
+}
+
+func (cr *Core) postCallback(taskID, url, secret string,
+	body []byte) (d entity.CallbackDelivery) {
+
+	d.Time = time.Now()
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		d.Error = fmt.Sprintf("create HTTP request: %s", err)
+		return
+	}
+
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(callbackTaskIDHeader, taskID)
+
+	if secret != "" {
+		req.Header.Set(callbackSignatureHeader,
+			signCallbackBody(secret, body))
+	}
+
+	res, err := cr.httpClient.Do(req)
+	if err != nil {
+		d.Error = fmt.Sprintf("HTTP post: %s", err)
+		return
+	}
+
+	err = res.Body.Close()
+	if err != nil {
+		cr.log.WithError(err).Error("failed to close HTTP response body")
+	}
+
+	d.StatusCode = res.StatusCode
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		d.Error = fmt.Sprintf("unexpected HTTP status %d", res.StatusCode)
+		return
+	}
+
+	d.Ok = true
+
+	return
+}
+
+func (cr *Core) getTaskCallbackDeliveries(c echo.Context) error {
+	t, err := cr.dbs.Task(c.Param("task-id"))
+	if err != nil {
+		if errors.Is(err, entity.ErrTaskNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound,
+				"task not found")
+		}
+		return fmt.Errorf("get task from DB storage: %w", err)
+	}
+
+	ds, err := cr.dbs.CallbackDeliveries(t.ID)
+	if err != nil {
+		return fmt.Errorf("get callback deliveries from DB storage: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, ds)
+}
@@ -0,0 +1,165 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo"
+
+	"github.com/dimuls/camtester/entity"
+)
+
+// resolveTaskCameras fills empty geo location and payloads of task and its
+// subtasks from referenced cameras. Subtasks inherit task camera.
+func (cr *Core) resolveTaskCameras(t *entity.Task) error {
+	cs := map[string]entity.Camera{}
+
+	camera := func(cameraID string) (entity.Camera, error) {
+		if c, ok := cs[cameraID]; ok {
+			return c, nil
+		}
+		c, err := cr.dbs.Camera(cameraID)
+		if err != nil {
+			return c, fmt.Errorf("get camera %s from DB storage: %w",
+				cameraID, err)
+		}
+		cs[cameraID] = c
+		return c, nil
+	}
+
+	if t.CameraID != "" {
+		c, err := camera(t.CameraID)
+		if err != nil {
+			return err
+		}
+
+		if t.GeoLocation == "" {
+			t.GeoLocation = c.GeoLocation
+		}
+
+		if t.Type != entity.ComplextTaskType && len(t.Payload) == 0 {
+			t.Payload, err = cr.cameraPayload(t.Type, c)
+			if err != nil {
+				return fmt.Errorf("make camera payload: %w", err)
+			}
+		}
+	}
+
+	for i := range t.Payloads {
+		st := &t.Payloads[i]
+
+		if st.CameraID == "" {
+			st.CameraID = t.CameraID
+		}
+
+		if st.CameraID == "" || len(st.Payload) != 0 {
+			continue
+		}
+
+		c, err := camera(st.CameraID)
+		if err != nil {
+			return fmt.Errorf("subtask #%d: %w", i, err)
+		}
+
+		st.Payload, err = cr.cameraPayload(st.Type, c)
+		if err != nil {
+			return fmt.Errorf("subtask #%d: make camera payload: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func (cr *Core) postCameras(c echo.Context) error {
+	var cam entity.Camera
+
+	err := json.NewDecoder(c.Request().Body).Decode(&cam)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Errorf("JSON decode camera: %w", err))
+	}
+
+	err = cam.Validate()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Errorf("camera validation: %w", err))
+	}
+
+	cam.ID = uuid.New().String()
+
+	err = cr.dbs.SetCamera(cam)
+	if err != nil {
+		return fmt.Errorf("set camera in DB storage: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, cam.ID)
+}
+
+func (cr *Core) getCameras(c echo.Context) error {
+	cs, err := cr.dbs.Cameras()
+	if err != nil {
+		return fmt.Errorf("get cameras from DB storage: %w", err)
+	}
+	return c.JSON(http.StatusOK, cs)
+}
+
+func (cr *Core) getCamera(c echo.Context) error {
+	cam, err := cr.dbs.Camera(c.Param("camera-id"))
+	if err != nil {
+		if errors.Is(err, entity.ErrCameraNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound,
+				"camera not found")
+		}
+		return fmt.Errorf("get camera from DB storage: %w", err)
+	}
+	return c.JSON(http.StatusOK, cam)
+}
+
+func (cr *Core) putCamera(c echo.Context) error {
+	var cam entity.Camera
+
+	err := json.NewDecoder(c.Request().Body).Decode(&cam)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Errorf("JSON decode camera: %w", err))
+	}
+
+	err = cam.Validate()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Errorf("camera validation: %w", err))
+	}
+
+	cam.ID = c.Param("camera-id")
+
+	_, err = cr.dbs.Camera(cam.ID)
+	if err != nil {
+		if errors.Is(err, entity.ErrCameraNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound,
+				"camera not found")
+		}
+		return fmt.Errorf("get camera from DB storage: %w", err)
+	}
+
+	err = cr.dbs.SetCamera(cam)
+	if err != nil {
+		return fmt.Errorf("set camera in DB storage: %w", err)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (cr *Core) deleteCamera(c echo.Context) error {
+	err := cr.dbs.DeleteCamera(c.Param("camera-id"))
+	if err != nil {
+		if errors.Is(err, entity.ErrCameraNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound,
+				"camera not found")
+		}
+		return fmt.Errorf("delete camera from DB storage: %w", err)
+	}
+	return c.NoContent(http.StatusOK)
+}
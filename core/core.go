@@ -21,27 +21,84 @@ import (
 type DBStorage interface {
 	Task(taskID string) (entity.Task, error)
 	SetTask(t entity.Task) error
+	UpdateTask(t entity.Task) error
+	Tasks(q entity.TaskQuery) (ts []entity.Task, nextCursor string, err error)
+
+	Schedules() ([]entity.Schedule, error)
+	Schedule(scheduleID string) (entity.Schedule, error)
+	SetSchedule(s entity.Schedule) error
+	DeleteSchedule(scheduleID string) error
+	LockSchedule(scheduleID string, fireTime time.Time) (bool, error)
+
+	AddAttemptDeadline(d entity.AttemptDeadline) error
+	RemoveAttemptDeadline(d entity.AttemptDeadline) (bool, error)
+	ExpiredAttemptDeadlines(now time.Time) ([]entity.AttemptDeadline, error)
+
+	AddPendingAttempt(pa entity.PendingAttempt) error
+	RemovePendingAttempt(pa entity.PendingAttempt) (bool, error)
+	DuePendingAttempts(now time.Time) ([]entity.PendingAttempt, error)
+
+	Cameras() ([]entity.Camera, error)
+	Camera(cameraID string) (entity.Camera, error)
+	SetCamera(c entity.Camera) error
+	DeleteCamera(cameraID string) error
+
+	CameraReference(cameraID string) (string, error)
+	SetCameraReference(cameraID, artifactID string) error
+
+	AddHistoryRecord(key string, r entity.HistoryRecord) error
+	History(key string, from, to time.Time) ([]entity.HistoryRecord, error)
+
+	AddCallbackDelivery(taskID string, d entity.CallbackDelivery) error
+	CallbackDeliveries(taskID string) ([]entity.CallbackDelivery, error)
+}
+
+type ArtifactStore interface {
+	Artifact(artifactID string) (entity.Artifact, error)
+	SetArtifact(entity.Artifact) error
 }
 
 type TaskPublisher interface {
 	PublishTask(entity.Task) error
+	PublishTaskCancel(taskID string) error
 }
 
+const taskUpdateMaxRetries = 10
+
+const (
+	tasksPageDefaultLimit = 100
+	tasksPageMaxLimit     = 1000
+)
+
 type Core struct {
 	dbs           DBStorage
 	taskPublisher TaskPublisher
+	artifacts     ArtifactStore
+	httpClient    *http.Client
+	resultBroker  *resultBroker
 	echo          *echo.Echo
-	log           *logrus.Entry
-	stop          chan struct{}
-	wg            sync.WaitGroup
+
+	// wsAllowedOrigins are origins of websocket requests allowed besides
+	// request host.
+	wsAllowedOrigins []string
+
+	log  *logrus.Entry
+	stop chan struct{}
+	wg   sync.WaitGroup
 }
 
-func NewCore(dbs DBStorage, tp TaskPublisher, bindAddr, jwtSecret string) *Core {
+func NewCore(dbs DBStorage, tp TaskPublisher, as ArtifactStore,
+	bindAddr, jwtSecret string, wsAllowedOrigins []string) *Core {
+
 	c := &Core{
-		dbs:           dbs,
-		taskPublisher: tp,
-		log:           logrus.WithField("subsystem", "core"),
-		stop:          make(chan struct{}),
+		dbs:              dbs,
+		taskPublisher:    tp,
+		artifacts:        as,
+		httpClient:       &http.Client{Timeout: callbackTimeout},
+		resultBroker:     newResultBroker(),
+		wsAllowedOrigins: wsAllowedOrigins,
+		log:              logrus.WithField("subsystem", "core"),
+		stop:             make(chan struct{}),
 	}
 
 	e := echo.New()
@@ -56,10 +113,33 @@ func NewCore(dbs DBStorage, tp TaskPublisher, bindAddr, jwtSecret string) *Core
 	e.Use(logrusLogger)
 	e.Use(middleware.JWT([]byte(jwtSecret)))
 
+	e.GET("/tasks", c.getTasks)
 	e.POST("/tasks", c.postTasks)
 	e.POST("/tasks-batch", c.postTasksBatch)
+	e.GET("/tasks/stream", c.getTasksStream)
+	e.GET("/tasks/stream/ws", c.getTasksStreamWS)
 	e.GET("/tasks/:task-id", c.getTask)
 	e.GET("/tasks/:task-id/result", c.getTaskResult)
+	e.GET("/tasks/:task-id/callback-deliveries", c.getTaskCallbackDeliveries)
+	e.DELETE("/tasks/:task-id", c.deleteTask)
+
+	e.POST("/cameras", c.postCameras)
+	e.GET("/cameras", c.getCameras)
+	e.GET("/cameras/:camera-id", c.getCamera)
+	e.PUT("/cameras/:camera-id", c.putCamera)
+	e.DELETE("/cameras/:camera-id", c.deleteCamera)
+	e.GET("/cameras/:camera-id/history", c.getCameraHistory)
+	e.GET("/cameras/:camera-id/reference", c.getCameraReference)
+	e.PUT("/cameras/:camera-id/reference", c.putCameraReference)
+	e.POST("/cameras/:camera-id/reference/refresh",
+		c.postCameraReferenceRefresh)
+
+	e.POST("/schedules", c.postSchedules)
+	e.GET("/schedules", c.getSchedules)
+	e.GET("/schedules/:schedule-id", c.getSchedule)
+	e.DELETE("/schedules/:schedule-id", c.deleteSchedule)
+
+	e.GET("/artifacts/:artifact-id", c.getArtifact)
 
 	c.wg.Add(1)
 	go func() {
@@ -83,6 +163,12 @@ func NewCore(dbs DBStorage, tp TaskPublisher, bindAddr, jwtSecret string) *Core
 		}
 	}()
 
+	c.wg.Add(1)
+	go c.runScheduler()
+
+	c.wg.Add(1)
+	go c.runSweeper()
+
 	return c
 }
 
@@ -102,66 +188,474 @@ func (cr *Core) Stop() (err error) {
 	return
 }
 
-func (cr *Core) publishTask(t entity.Task) (err error) {
+// taskTimeout returns timeout of task attempt: task timeout if it is set or
+// default timeout of task type.
+func taskTimeout(t entity.Task) time.Duration {
+	if t.TimeoutSec > 0 {
+		return time.Duration(t.TimeoutSec) * time.Second
+	}
+	if tt, ok := taskTypes[t.Type]; ok {
+		return tt.timeout
+	}
+	return defaultTaskTimeout
+}
+
+// newAttempt creates attempt of simple task or subtask of complex task and
+// adds it to task.
+func newAttempt(t *entity.Task, subtaskIndex *int,
+	publishAt time.Time) entity.TaskAttempt {
+
+	pt := *t
+	if subtaskIndex != nil {
+		pt = t.Subtask(*subtaskIndex)
+	}
+
+	a := entity.TaskAttempt{
+		ID:           uuid.New().String(),
+		SubtaskIndex: subtaskIndex,
+		PublishedAt:  publishAt,
+		Deadline:     publishAt.Add(taskTimeout(pt)),
+	}
+
+	t.Attempts = append(t.Attempts, a)
+
+	return a
+}
+
+// newAttempts creates attempts of simple task if it is not published yet or
+// of ready subtasks of complex task and adds them to task. Task should be
+// stored before attempts are published.
+func newAttempts(t *entity.Task) (as []entity.TaskAttempt) {
+	now := time.Now()
+
 	if t.Type == entity.ComplextTaskType {
-		st := t.Payloads[len(t.Results)]
-		st.ID = t.ID
-		st.GeoLocation = t.GeoLocation
-		st.Results = nil
-		st.Result = nil
-		defer func(subtaskIndex int) {
-			if err == nil {
-				logrus.WithFields(logrus.Fields{
-					"task_id":       t.ID,
-					"subtask_index": subtaskIndex,
-				}).Info("complex task subtask published")
-			}
-		}(len(t.Results))
-		t = st
+		for _, i := range t.ReadySubtasks() {
+			i := i
+			as = append(as, newAttempt(t, &i, now))
+		}
+	} else if len(t.Attempts) == 0 {
+		as = append(as, newAttempt(t, nil, now))
 	}
-	return cr.taskPublisher.PublishTask(t)
+
+	return
 }
 
-func (cr *Core) HandleTaskResult(tr entity.TaskResult) (err error) {
-	log := cr.log.WithField("task_id", tr.TaskID)
+// scheduleAttempts adds deadlines and pending entries of attempts to DB
+// storage. They are added before attempts are stored in task, so every
+// stored attempt is swept and published even after core restart. Entries
+// of attempts which are not stored are skipped and removed by sweeper.
+func (cr *Core) scheduleAttempts(taskID string,
+	as []entity.TaskAttempt) error {
+
+	for _, a := range as {
+		err := cr.dbs.AddAttemptDeadline(entity.AttemptDeadline{
+			TaskID:    taskID,
+			AttemptID: a.ID,
+			Deadline:  a.Deadline,
+		})
+		if err != nil {
+			return fmt.Errorf("add attempt %s deadline to DB storage: %w",
+				a.ID, err)
+		}
 
-	log.Debug("task result received")
+		err = cr.dbs.AddPendingAttempt(entity.PendingAttempt{
+			TaskID:    taskID,
+			AttemptID: a.ID,
+			PublishAt: a.PublishedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("add pending attempt %s to DB storage: %w",
+				a.ID, err)
+		}
+	}
 
-	t, err := cr.dbs.Task(tr.TaskID)
+	return nil
+}
+
+// publishTask publishes given stored attempts of simple task or subtasks of
+// complex task which publish time is come. Attempts which publish time is
+// in future or which are failed to be published are left to sweeper.
+func (cr *Core) publishTask(t entity.Task, as []entity.TaskAttempt) {
+	now := time.Now()
+
+	for _, a := range as {
+		if a.PublishedAt.After(now) {
+			continue
+		}
+
+		err := cr.claimAndPublishAttempt(t, a)
+		if err != nil {
+			cr.log.WithError(err).WithFields(logrus.Fields{
+				"task_id":    t.ID,
+				"attempt_id": a.ID,
+			}).Error("failed to publish attempt")
+		}
+	}
+}
+
+// claimAndPublishAttempt removes pending entry of attempt and publishes
+// attempt if entry is removed by this call, so attempt is published once
+// by core or sweeper. Attempt which fails to be published gets failed
+// result.
+func (cr *Core) claimAndPublishAttempt(t entity.Task,
+	a entity.TaskAttempt) error {
+
+	claimed, err := cr.dbs.RemovePendingAttempt(entity.PendingAttempt{
+		TaskID:    t.ID,
+		AttemptID: a.ID,
+	})
 	if err != nil {
-		logrus.WithError(err).Error("failed to get task from DB storage")
-		if err == entity.ErrTaskNotFound {
-			return nil
+		return fmt.Errorf("remove pending attempt %s from DB storage: %w",
+			a.ID, err)
+	}
+	if !claimed {
+		return nil
+	}
+
+	err = cr.publishAttempt(t, a)
+	if err != nil {
+		return cr.failAttempt(t.ID, a, err)
+	}
+
+	return nil
+}
+
+// failAttempt writes failed result of attempt which can't be published, so
+// attempt is retried by retry policy or task is finished instead of being
+// pending forever.
+func (cr *Core) failAttempt(taskID string, a entity.TaskAttempt,
+	publishErr error) error {
+
+	cr.log.WithError(publishErr).WithFields(logrus.Fields{
+		"task_id":    taskID,
+		"attempt_id": a.ID,
+	}).Error("failed to publish attempt, writing failed result")
+
+	payload, err := json.Marshal(entity.NewTaskError(entity.ErrorCodeInternal,
+		entity.ErrorStageCore, "failed to publish task: "+publishErr.Error()))
+	if err != nil {
+		return fmt.Errorf("JSON marshal publish error payload: %w", err)
+	}
+
+	err = cr.HandleTaskResult(entity.TaskResult{
+		TaskID:    taskID,
+		AttemptID: a.ID,
+		Time:      time.Now(),
+		Reason:    entity.ErrorCodeInternal,
+		Payload:   payload,
+	})
+	if err != nil {
+		return fmt.Errorf("handle failed attempt %s result: %w", a.ID, err)
+	}
+
+	return nil
+}
+
+func (cr *Core) publishAttempt(t entity.Task, a entity.TaskAttempt) error {
+	pt := t
+	pt.Attempts = nil
+	pt.Callback = nil
+
+	if a.SubtaskIndex != nil {
+		pt = t.Subtask(*a.SubtaskIndex)
+	}
+
+	deadline := a.Deadline
+
+	pt.AttemptID = a.ID
+	pt.Deadline = &deadline
+
+	err := cr.taskPublisher.PublishTask(pt)
+	if err != nil {
+		return fmt.Errorf("publish attempt %s: %w", a.ID, err)
+	}
+
+	log := cr.log.WithFields(logrus.Fields{
+		"task_id":    t.ID,
+		"attempt_id": a.ID,
+	})
+
+	if a.SubtaskIndex != nil {
+		log.WithField("subtask_index", *a.SubtaskIndex).
+			Info("complex task subtask published")
+	} else {
+		log.Debug("task published")
+	}
+
+	return nil
+}
+
+// isDuplicateResult returns true if result of the same task or subtask is
+// already handled, for example when task result is redelivered.
+func isDuplicateResult(t entity.Task, tr entity.TaskResult) bool {
+	if t.Type != entity.ComplextTaskType {
+		return t.Result != nil
+	}
+	return t.SubtaskResult(*tr.SubtaskIndex) != nil
+}
+
+// resultTask returns task or subtask of complex task which result is.
+func resultTask(t entity.Task, tr entity.TaskResult) entity.Task {
+	if t.Type != entity.ComplextTaskType || tr.SubtaskIndex == nil {
+		return t
+	}
+	return t.Subtask(*tr.SubtaskIndex)
+}
+
+func (cr *Core) createTask(t entity.Task) (string, error) {
+	resetServerFields(&t)
+
+	err := validateTaskTypes(t)
+	if err != nil {
+		return "", err
+	}
+
+	err = cr.resolveTaskCameras(&t)
+	if err != nil {
+		return "", fmt.Errorf("resolve task cameras: %w", err)
+	}
+
+	t.ID = uuid.New().String()
+	t.CreatedAt = time.Now()
+
+	as := newAttempts(&t)
+
+	err = cr.scheduleAttempts(t.ID, as)
+	if err != nil {
+		return "", err
+	}
+
+	err = cr.dbs.SetTask(t)
+	if err != nil {
+		return "", fmt.Errorf("set task in DB storage: %w", err)
+	}
+
+	cr.publishTask(t, as)
+
+	return t.ID, nil
+}
+
+// resetServerFields zeroes fields of task and its subtasks which are set by
+// core, so they can't be set by client. Subtasks are copied, so caller's
+// task is not changed.
+func resetServerFields(t *entity.Task) {
+	t.Result = nil
+	t.Results = nil
+	t.Attempts = nil
+	t.SubtaskIndex = nil
+	t.AttemptID = ""
+	t.Deadline = nil
+	t.Cancelled = false
+	t.Version = 0
+
+	if t.Payloads != nil {
+		t.Payloads = append([]entity.Task(nil), t.Payloads...)
+	}
+
+	for i := range t.Payloads {
+		resetServerFields(&t.Payloads[i])
+	}
+}
+
+// invalidTaskErrors are errors of task creation caused by task content.
+var invalidTaskErrors = []error{
+	entity.ErrUnknownTaskType,
+	entity.ErrCameraNotFound,
+	entity.ErrCameraReferenceNotFound,
+	entity.ErrCameraProfileNotFound,
+}
+
+// invalidTaskError returns message of task creation error if it is caused by
+// task content, for example referenced camera is not found.
+func invalidTaskError(err error) (string, bool) {
+	for _, ite := range invalidTaskErrors {
+		if errors.Is(err, ite) {
+			return ite.Error(), true
 		}
-		return err
 	}
+	return "", false
+}
 
-	tr.TaskID = ""
+// errSkipTaskUpdate is returned by task update function when task should
+// not be updated.
+var errSkipTaskUpdate = errors.New("skip task update")
 
-	if t.Type == entity.ComplextTaskType {
-		t.Results = append(t.Results, tr)
+// updateTask gets task, updates it using given function and stores it if
+// task is not updated concurrently since get. On conflict update is retried
+// with fresh task, so update function should not have side effects.
+// Deadlines and pending entries of attempts added by update are stored
+// before task.
+func (cr *Core) updateTask(taskID string,
+	update func(t *entity.Task) error) (t entity.Task, err error) {
 
-		err = cr.dbs.SetTask(t)
+	for i := 0; ; i++ {
+		t, err = cr.dbs.Task(taskID)
 		if err != nil {
-			return err
+			return t, fmt.Errorf("get task from DB storage: %w", err)
 		}
 
-		if tr.Ok && len(t.Results) < len(t.Payloads) {
-			err = cr.publishTask(t)
-			if err != nil {
-				return err
+		known := map[string]bool{}
+		for _, a := range t.Attempts {
+			known[a.ID] = true
+		}
+
+		err = update(&t)
+		if err != nil {
+			return
+		}
+
+		var as []entity.TaskAttempt
+
+		for _, a := range t.Attempts {
+			if !known[a.ID] {
+				as = append(as, a)
 			}
 		}
 
-	} else {
+		err = cr.scheduleAttempts(t.ID, as)
+		if err != nil {
+			return
+		}
+
+		err = cr.dbs.UpdateTask(t)
+		if err == nil {
+			t.Version++
+			return
+		}
+
+		if !errors.Is(err, entity.ErrTaskVersionConflict) ||
+			i == taskUpdateMaxRetries {
+			return t, fmt.Errorf("update task in DB storage: %w", err)
+		}
+
+		cr.log.WithField("task_id", taskID).Debug(
+			"task update conflict, retrying")
+	}
+}
+
+// recordTaskResult adds result to task and creates attempts of subtasks
+// which are ready after it. If failed result is retried, it's kept in its
+// attempt and retry attempts are returned. Results of cancelled task are
+// discarded, so cancelled task is never finished.
+func (cr *Core) recordTaskResult(t *entity.Task, tr entity.TaskResult) (
+	rtr entity.TaskResult, as []entity.TaskAttempt, retried bool,
+	err error) {
+
+	log := cr.log.WithField("task_id", t.ID)
+
+	if t.Cancelled {
+		log.WithField("attempt_id", tr.AttemptID).Info(
+			"task result of cancelled task discarded")
+		return tr, nil, false, errSkipTaskUpdate
+	}
+
+	if tr.AttemptID != "" {
+		a := t.Attempt(tr.AttemptID)
+		if a == nil {
+			log.WithField("attempt_id", tr.AttemptID).Warn(
+				"task result of unknown attempt received")
+			return tr, nil, false, errSkipTaskUpdate
+		}
+		tr.SubtaskIndex = a.SubtaskIndex
+	}
+
+	if t.Type == entity.ComplextTaskType {
+		i := len(t.Results)
+		if tr.SubtaskIndex != nil {
+			i = *tr.SubtaskIndex
+		}
+
+		if i < 0 || i >= len(t.Payloads) {
+			log.WithField("subtask_index", i).Error(
+				"task result of unknown subtask received")
+			return tr, nil, false, errSkipTaskUpdate
+		}
+
+		tr.SubtaskIndex = &i
+	}
+
+	if isDuplicateResult(*t, tr) {
+		log.WithField("attempt_id", tr.AttemptID).Info(
+			"duplicate task result received")
+		return tr, nil, false, errSkipTaskUpdate
+	}
+
+	as, retried = retryTask(t, tr)
+	if retried {
+		return tr, as, true, nil
+	}
+
+	if t.Type != entity.ComplextTaskType {
 		t.Result = &tr
+		return tr, nil, false, nil
+	}
+
+	t.Results = append(t.Results, tr)
+
+	return tr, newAttempts(t), false, nil
+}
+
+func (cr *Core) HandleTaskResult(tr entity.TaskResult) (err error) {
+	log := cr.log.WithField("task_id", tr.TaskID)
+
+	log.Debug("task result received")
 
-		err = cr.dbs.SetTask(t)
+	var (
+		wasFinished bool
+		retried     bool
+		as          []entity.TaskAttempt
+		rtr         entity.TaskResult
+	)
+
+	t, err := cr.updateTask(tr.TaskID, func(t *entity.Task) (err error) {
+		wasFinished = t.Finished()
+		tr := tr
+		tr.TaskID = ""
+		rtr, as, retried, err = cr.recordTaskResult(t, tr)
+		return
+	})
+	if err != nil {
+		if errors.Is(err, errSkipTaskUpdate) {
+			return nil
+		}
+		log.WithError(err).Error("failed to update task")
+		if errors.Is(err, entity.ErrTaskNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	cr.publishTask(t, as)
+
+	if rtr.AttemptID != "" {
+		_, err = cr.dbs.RemoveAttemptDeadline(entity.AttemptDeadline{
+			TaskID:    t.ID,
+			AttemptID: rtr.AttemptID,
+		})
 		if err != nil {
-			return err
+			log.WithError(err).Error(
+				"failed to remove attempt deadline from DB storage")
 		}
 	}
 
+	if retried {
+		log.WithFields(logrus.Fields{
+			"attempt_id": rtr.AttemptID,
+			"reason":     rtr.Reason,
+		}).Info("failed task attempt is retried")
+		return nil
+	}
+
+	cr.addHistoryRecord(t, rtr)
+
+	cr.storeCapturedReference(t, rtr)
+
+	cr.resultBroker.publish(newTaskResultEvent(t, rtr))
+
+	if !wasFinished && t.Finished() && t.Callback != nil {
+		cr.deliverCallback(t)
+	}
+
 	log.Debug("task result successfully handled")
 
 	return
@@ -182,21 +676,15 @@ func (cr *Core) postTasks(c echo.Context) error {
 			fmt.Errorf("task validation: %w", err))
 	}
 
-	t.ID = uuid.New().String()
-	t.Result = nil
-	t.Results = nil
-
-	err = cr.dbs.SetTask(t)
-	if err != nil {
-		return fmt.Errorf("set task in DB storage: %w", err)
-	}
-
-	err = cr.publishTask(t)
+	id, err := cr.createTask(t)
 	if err != nil {
-		return fmt.Errorf("publish task: %w", err)
+		if msg, ok := invalidTaskError(err); ok {
+			return echo.NewHTTPError(http.StatusBadRequest, msg)
+		}
+		return err
 	}
 
-	return c.JSON(http.StatusOK, t.ID)
+	return c.JSON(http.StatusOK, id)
 }
 
 func (cr *Core) postTasksBatch(c echo.Context) error {
@@ -218,25 +706,104 @@ func (cr *Core) postTasksBatch(c echo.Context) error {
 
 	var ids []string
 
-	for _, t := range ts {
-		t.ID = uuid.New().String()
-		t.Result = nil
-		t.Results = nil
+	for i, t := range ts {
+		id, err := cr.createTask(t)
+		if err != nil {
+			if msg, ok := invalidTaskError(err); ok {
+				return echo.NewHTTPError(http.StatusBadRequest,
+					fmt.Sprintf("task #%d: %s", i, msg))
+			}
+			return err
+		}
+
+		ids = append(ids, id)
+	}
 
-		err = cr.dbs.SetTask(t)
+	return c.JSON(http.StatusOK, ids)
+}
+
+type tasksPage struct {
+	Tasks      []entity.Task `json:"tasks"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+func (cr *Core) getTasks(c echo.Context) error {
+	q := entity.TaskQuery{
+		Type:        c.QueryParam("type"),
+		GeoLocation: c.QueryParam("geo_location"),
+		Status:      c.QueryParam("status"),
+		PayloadURI:  c.QueryParam("payload"),
+		Cursor:      c.QueryParam("cursor"),
+	}
+
+	switch q.Status {
+	case "", entity.TaskStatusPending, entity.TaskStatusFinished,
+		entity.TaskStatusCancelled:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid status")
+	}
+
+	if okStr := c.QueryParam("ok"); okStr != "" {
+		ok, err := strconv.ParseBool(okStr)
 		if err != nil {
-			return fmt.Errorf("set task in DB storage: %w", err)
+			return echo.NewHTTPError(http.StatusBadRequest,
+				fmt.Errorf("parse ok: %w", err))
 		}
+		q.Ok = &ok
+	}
 
-		err = cr.publishTask(t)
+	if fromStr := c.QueryParam("from"); fromStr != "" {
+		from, err := time.Parse(time.RFC3339, fromStr)
 		if err != nil {
-			return fmt.Errorf("publish task: %w", err)
+			return echo.NewHTTPError(http.StatusBadRequest,
+				fmt.Errorf("parse from: %w", err))
 		}
+		q.From = from
+	}
 
-		ids = append(ids, t.ID)
+	if toStr := c.QueryParam("to"); toStr != "" {
+		to, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				fmt.Errorf("parse to: %w", err))
+		}
+		q.To = to
 	}
 
-	return c.JSON(http.StatusOK, ids)
+	if limitStr := c.QueryParam("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				fmt.Errorf("parse limit: %w", err))
+		}
+		q.Limit = limit
+	}
+
+	if q.Limit <= 0 || q.Limit > tasksPageMaxLimit {
+		q.Limit = tasksPageDefaultLimit
+	}
+
+	ts, nextCursor, err := cr.dbs.Tasks(q)
+	if err != nil {
+		if errors.Is(err, entity.ErrInvalidCursor) {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				"invalid cursor")
+		}
+		return fmt.Errorf("get tasks from DB storage: %w", err)
+	}
+
+	if ts == nil {
+		ts = []entity.Task{}
+	}
+
+	for i := range ts {
+		ts[i] = hideCallbackSecret(ts[i])
+	}
+
+	return c.JSON(http.StatusOK, tasksPage{
+		Tasks:      ts,
+		NextCursor: nextCursor,
+	})
 }
 
 func (cr *Core) getTask(c echo.Context) error {
@@ -248,7 +815,7 @@ func (cr *Core) getTask(c echo.Context) error {
 		}
 		return fmt.Errorf("get task from DB storage: %w", err)
 	}
-	return c.JSON(http.StatusOK, t)
+	return c.JSON(http.StatusOK, hideCallbackSecret(t))
 }
 
 func (cr *Core) getTaskResult(c echo.Context) error {
@@ -274,6 +841,40 @@ func (cr *Core) getTaskResult(c echo.Context) error {
 	return c.JSON(http.StatusOK, t.Result)
 }
 
+func (cr *Core) deleteTask(c echo.Context) error {
+	var finished bool
+
+	t, err := cr.updateTask(c.Param("task-id"), func(t *entity.Task) error {
+		finished = t.Finished()
+		if finished || t.Cancelled {
+			return errSkipTaskUpdate
+		}
+		t.Cancelled = true
+		return nil
+	})
+	if err != nil && !errors.Is(err, errSkipTaskUpdate) {
+		if errors.Is(err, entity.ErrTaskNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound,
+				"task not found")
+		}
+		return fmt.Errorf("update task: %w", err)
+	}
+
+	if finished {
+		return echo.NewHTTPError(http.StatusConflict,
+			"task is already finished")
+	}
+
+	err = cr.taskPublisher.PublishTaskCancel(t.ID)
+	if err != nil {
+		return fmt.Errorf("publish task cancel: %w", err)
+	}
+
+	cr.log.WithField("task_id", t.ID).Info("task cancelled")
+
+	return c.NoContent(http.StatusOK)
+}
+
 func (cr *Core) httpErrorHandler(err error, ctx echo.Context) {
 	var (
 		code = http.StatusInternalServerError
@@ -0,0 +1,506 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/dimuls/camtester/entity"
+)
+
+// memoryStorage is in-memory DB storage of methods used by task result
+// handling. Tasks are kept JSON marshaled like in redis, so updates don't
+// share memory. Every conflictEvery-th task update fails with version
+// conflict. Task update is delayed by latency, so concurrent updates
+// interleave.
+type memoryStorage struct {
+	DBStorage
+
+	conflictEvery int
+	latency       time.Duration
+
+	tasks      map[string][]byte
+	updates    int
+	deadlines  map[string]entity.AttemptDeadline
+	pending    map[string]entity.PendingAttempt
+	deliveries map[string][]entity.CallbackDelivery
+	mx         sync.Mutex
+}
+
+func newMemoryStorage(conflictEvery int,
+	latency time.Duration) *memoryStorage {
+	return &memoryStorage{
+		conflictEvery: conflictEvery,
+		latency:       latency,
+		tasks:         map[string][]byte{},
+		deadlines:     map[string]entity.AttemptDeadline{},
+		pending:       map[string]entity.PendingAttempt{},
+		deliveries:    map[string][]entity.CallbackDelivery{},
+	}
+}
+
+func (s *memoryStorage) Task(taskID string) (t entity.Task, err error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	tJSON, ok := s.tasks[taskID]
+	if !ok {
+		return t, entity.ErrTaskNotFound
+	}
+
+	err = json.Unmarshal(tJSON, &t)
+
+	return
+}
+
+func (s *memoryStorage) SetTask(t entity.Task) (err error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.tasks[t.ID], err = json.Marshal(t)
+
+	return
+}
+
+func (s *memoryStorage) UpdateTask(t entity.Task) error {
+	time.Sleep(s.latency)
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.updates++
+
+	if s.conflictEvery != 0 && s.updates%s.conflictEvery == 0 {
+		return entity.ErrTaskVersionConflict
+	}
+
+	tJSON, ok := s.tasks[t.ID]
+	if !ok {
+		return entity.ErrTaskNotFound
+	}
+
+	var st entity.Task
+
+	err := json.Unmarshal(tJSON, &st)
+	if err != nil {
+		return err
+	}
+
+	if st.Version != t.Version {
+		return entity.ErrTaskVersionConflict
+	}
+
+	t.Version++
+
+	s.tasks[t.ID], err = json.Marshal(t)
+
+	return err
+}
+
+func (s *memoryStorage) AddAttemptDeadline(d entity.AttemptDeadline) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.deadlines[d.TaskID+":"+d.AttemptID] = d
+
+	return nil
+}
+
+func (s *memoryStorage) RemoveAttemptDeadline(
+	d entity.AttemptDeadline) (bool, error) {
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	_, ok := s.deadlines[d.TaskID+":"+d.AttemptID]
+	delete(s.deadlines, d.TaskID+":"+d.AttemptID)
+
+	return ok, nil
+}
+
+func (s *memoryStorage) AddPendingAttempt(pa entity.PendingAttempt) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.pending[pa.TaskID+":"+pa.AttemptID] = pa
+
+	return nil
+}
+
+func (s *memoryStorage) RemovePendingAttempt(
+	pa entity.PendingAttempt) (bool, error) {
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	_, ok := s.pending[pa.TaskID+":"+pa.AttemptID]
+	delete(s.pending, pa.TaskID+":"+pa.AttemptID)
+
+	return ok, nil
+}
+
+func (s *memoryStorage) AddHistoryRecord(key string,
+	r entity.HistoryRecord) error {
+	return nil
+}
+
+func (s *memoryStorage) AddCallbackDelivery(taskID string,
+	d entity.CallbackDelivery) error {
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.deliveries[taskID] = append(s.deliveries[taskID], d)
+
+	return nil
+}
+
+func (s *memoryStorage) CallbackDeliveries(
+	taskID string) ([]entity.CallbackDelivery, error) {
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return s.deliveries[taskID], nil
+}
+
+// memoryPublisher keeps published tasks.
+type memoryPublisher struct {
+	tasks []entity.Task
+	mx    sync.Mutex
+}
+
+func (p *memoryPublisher) PublishTask(t entity.Task) error {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	p.tasks = append(p.tasks, t)
+
+	return nil
+}
+
+func (p *memoryPublisher) PublishTaskCancel(taskID string) error {
+	return nil
+}
+
+func (p *memoryPublisher) published() []entity.Task {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	return append([]entity.Task(nil), p.tasks...)
+}
+
+func newTestCore(dbs DBStorage, tp TaskPublisher) *Core {
+	return &Core{
+		dbs:           dbs,
+		taskPublisher: tp,
+		httpClient:    &http.Client{Timeout: callbackTimeout},
+		resultBroker:  newResultBroker(),
+		log:           logrus.WithField("subsystem", "core"),
+		stop:          make(chan struct{}),
+	}
+}
+
+func TestHandleTaskResultConcurrent(t *testing.T) {
+	const (
+		subtasks   = 16
+		deliveries = 3
+
+		// maxRedeliveries is how many times result is redelivered if its
+		// handling fails, like nats redelivers not acknowledged message.
+		maxRedeliveries = 10
+	)
+
+	var callbacks int32
+
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&callbacks, 1)
+			w.WriteHeader(http.StatusOK)
+		}))
+	defer srv.Close()
+
+	dbs := newMemoryStorage(3, time.Millisecond)
+	tp := &memoryPublisher{}
+	cr := newTestCore(dbs, tp)
+
+	task := entity.Task{
+		Type:        entity.ComplextTaskType,
+		GeoLocation: "moscow",
+		Parallel:    true,
+		Callback:    &entity.Callback{URL: srv.URL},
+	}
+
+	for i := 0; i < subtasks; i++ {
+		task.Payloads = append(task.Payloads, entity.Task{
+			Type:    "ping",
+			Payload: json.RawMessage(fmt.Sprintf(`"10.0.0.%d"`, i)),
+		})
+	}
+
+	taskID, err := cr.createTask(task)
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	published := tp.published()
+	if len(published) != subtasks {
+		t.Fatalf("expected %d published subtasks, got %d", subtasks,
+			len(published))
+	}
+
+	var wg sync.WaitGroup
+
+	// Every result is delivered several times concurrently, like nats
+	// redelivers messages.
+	for _, st := range published {
+		for d := 0; d < deliveries; d++ {
+			wg.Add(1)
+			go func(st entity.Task) {
+				defer wg.Done()
+
+				tr := st.NewResult()
+				tr.Ok = true
+				tr.Time = time.Now()
+
+				for i := 0; ; i++ {
+					err := cr.HandleTaskResult(tr)
+					if err == nil {
+						return
+					}
+					if i == maxRedeliveries {
+						t.Errorf("failed to handle task result: %v", err)
+						return
+					}
+				}
+			}(st)
+		}
+	}
+
+	wg.Wait()
+	cr.wg.Wait()
+
+	ft, err := dbs.Task(taskID)
+	if err != nil {
+		t.Fatalf("failed to get task: %v", err)
+	}
+
+	if !ft.Succeeded() {
+		t.Errorf("expected succeeded task, got status %s", ft.Status())
+	}
+
+	if len(ft.Results) != subtasks {
+		t.Errorf("expected %d results, got %d", subtasks, len(ft.Results))
+	}
+
+	seen := map[int]bool{}
+
+	for _, tr := range ft.Results {
+		if tr.SubtaskIndex == nil {
+			t.Errorf("result without subtask index")
+			continue
+		}
+		if seen[*tr.SubtaskIndex] {
+			t.Errorf("duplicate result of subtask #%d", *tr.SubtaskIndex)
+		}
+		seen[*tr.SubtaskIndex] = true
+	}
+
+	for i := 0; i < subtasks; i++ {
+		if !seen[i] {
+			t.Errorf("lost result of subtask #%d", i)
+		}
+	}
+
+	if n := atomic.LoadInt32(&callbacks); n != 1 {
+		t.Errorf("expected callback to be delivered once, got %d", n)
+	}
+
+	ds, err := dbs.CallbackDeliveries(taskID)
+	if err != nil {
+		t.Fatalf("failed to get callback deliveries: %v", err)
+	}
+
+	if len(ds) != 1 || !ds[0].Ok {
+		t.Errorf("expected one successful callback delivery, got %v", ds)
+	}
+
+	if len(dbs.deadlines) != 0 {
+		t.Errorf("expected no attempt deadlines left, got %d",
+			len(dbs.deadlines))
+	}
+}
+
+// cameraStorage is memory storage with cameras.
+type cameraStorage struct {
+	*memoryStorage
+	cameras map[string]entity.Camera
+}
+
+func (s cameraStorage) Camera(cameraID string) (entity.Camera, error) {
+	c, ok := s.cameras[cameraID]
+	if !ok {
+		return c, entity.ErrCameraNotFound
+	}
+	return c, nil
+}
+
+func TestCreateTaskInvalid(t *testing.T) {
+	dbs := cameraStorage{
+		memoryStorage: newMemoryStorage(0, 0),
+		cameras: map[string]entity.Camera{
+			"camera": {
+				ID:      "camera",
+				Name:    "camera",
+				RTSPURI: "rtsp://camera/stream",
+			},
+		},
+	}
+
+	cr := newTestCore(dbs, &memoryPublisher{})
+
+	for _, c := range []struct {
+		name string
+		task entity.Task
+		err  error
+	}{
+		{
+			name: "unknown type",
+			task: entity.Task{Type: "unknown", CameraID: "camera"},
+			err:  entity.ErrUnknownTaskType,
+		},
+		{
+			name: "unknown subtask type",
+			task: entity.Task{
+				Type: entity.ComplextTaskType,
+				Payloads: []entity.Task{
+					{Type: "ping", CameraID: "camera"},
+					{Type: "unknown", CameraID: "camera"},
+				},
+			},
+			err: entity.ErrUnknownTaskType,
+		},
+		{
+			name: "camera not found",
+			task: entity.Task{Type: "ping", CameraID: "missing"},
+			err:  entity.ErrCameraNotFound,
+		},
+		{
+			name: "conformance without profile",
+			task: entity.Task{Type: "conformance", CameraID: "camera"},
+			err:  entity.ErrCameraProfileNotFound,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := cr.createTask(c.task)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("expected error %v, got %v", c.err, err)
+			}
+			if _, ok := invalidTaskError(err); !ok {
+				t.Errorf("expected invalid task error, got %v", err)
+			}
+		})
+	}
+
+	if n := len(dbs.tasks); n != 0 {
+		t.Errorf("expected no tasks created, got %d", n)
+	}
+}
+
+func TestCreateTaskResetsServerFields(t *testing.T) {
+	dbs := newMemoryStorage(0, 0)
+	cr := newTestCore(dbs, &memoryPublisher{})
+
+	i := 1
+	deadline := time.Now()
+
+	serverFields := entity.Task{
+		Result:       &entity.TaskResult{},
+		Results:      []entity.TaskResult{{}},
+		Attempts:     []entity.TaskAttempt{{ID: "attempt"}},
+		SubtaskIndex: &i,
+		AttemptID:    "attempt",
+		Deadline:     &deadline,
+		Cancelled:    true,
+		Version:      10,
+	}
+
+	task := serverFields
+	task.Type = entity.ComplextTaskType
+	task.GeoLocation = "moscow"
+
+	for i := 0; i < 2; i++ {
+		st := serverFields
+		st.Type = "ping"
+		st.Payload = json.RawMessage(`"10.0.0.1"`)
+		task.Payloads = append(task.Payloads, st)
+	}
+
+	taskID, err := cr.createTask(task)
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	ct, err := dbs.Task(taskID)
+	if err != nil {
+		t.Fatalf("failed to get task: %v", err)
+	}
+
+	check := func(name string, t2 entity.Task) {
+		if t2.Result != nil || t2.Results != nil || t2.SubtaskIndex != nil ||
+			t2.Deadline != nil || t2.Cancelled || t2.Version != 0 {
+			t.Errorf("%s: server fields are not reset: %+v", name, t2)
+		}
+	}
+
+	check("task", ct)
+
+	for _, a := range ct.Attempts {
+		if a.ID == "attempt" {
+			t.Errorf("client attempt is kept")
+		}
+	}
+
+	for i, st := range ct.Payloads {
+		name := fmt.Sprintf("subtask #%d", i)
+		check(name, st)
+		if st.Attempts != nil || st.AttemptID != "" {
+			t.Errorf("%s: attempts are not reset", name)
+		}
+	}
+
+	if task.Payloads[0].Version != 10 {
+		t.Errorf("caller's subtask is changed")
+	}
+}
+
+func TestCheckWSOrigin(t *testing.T) {
+	cr := newTestCore(nil, nil)
+	cr.wsAllowedOrigins = []string{"https://dashboard.example.com"}
+
+	for origin, allowed := range map[string]bool{
+		"":                              true,
+		"http://core.example.com":       true,
+		"https://dashboard.example.com": true,
+		"https://evil.example.com":      false,
+		"http://core.example.com.evil":  false,
+	} {
+		r := httptest.NewRequest(http.MethodGet,
+			"http://core.example.com/tasks/stream/ws", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		if got := cr.checkWSOrigin(r); got != allowed {
+			t.Errorf("origin %q: expected allowed %v, got %v", origin,
+				allowed, got)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo"
+
+	"github.com/dimuls/camtester/entity"
+)
+
+const defaultHistoryPeriod = 24 * time.Hour
+
+// addHistoryRecord appends task result to history of camera or URI of task
+// or subtask which result is. Errors are only logged since history is not
+// critical for task result handling.
+func (cr *Core) addHistoryRecord(t entity.Task, tr entity.TaskResult) {
+	st := resultTask(t, tr)
+
+	key := st.HistoryKey()
+	if key == "" {
+		return
+	}
+
+	err := cr.dbs.AddHistoryRecord(key, entity.HistoryRecord{
+		Time:     tr.Time,
+		TaskID:   t.ID,
+		TaskType: st.Type,
+		Ok:       tr.Ok,
+		Payload:  tr.Payload,
+	})
+	if err != nil {
+		cr.log.WithError(err).WithField("task_id", t.ID).Error(
+			"failed to add history record to DB storage")
+	}
+}
+
+// historyMetric returns numeric field of record payload. Nested fields are
+// separated by dot.
+func historyMetric(r entity.HistoryRecord, metric string) (float64, bool) {
+	var v interface{}
+
+	err := json.Unmarshal(r.Payload, &v)
+	if err != nil {
+		return 0, false
+	}
+
+	for _, f := range strings.Split(metric, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return 0, false
+		}
+		v, ok = m[f]
+		if !ok {
+			return 0, false
+		}
+	}
+
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case bool:
+		if n {
+			return 1, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// downsampleHistory aggregates metric of ok records into points of step
+// length starting from from. Zero step means point per record.
+func downsampleHistory(rs []entity.HistoryRecord, metric string,
+	from time.Time, step time.Duration) []entity.HistoryPoint {
+
+	ps := []entity.HistoryPoint{}
+
+	for _, r := range rs {
+		if !r.Ok {
+			continue
+		}
+
+		v, ok := historyMetric(r, metric)
+		if !ok {
+			continue
+		}
+
+		pt := r.Time
+		if step > 0 {
+			pt = from.Add(r.Time.Sub(from) / step * step)
+		}
+
+		if len(ps) == 0 || !ps[len(ps)-1].Time.Equal(pt) {
+			ps = append(ps, entity.HistoryPoint{
+				Time: pt,
+				Min:  math.Inf(1),
+				Max:  math.Inf(-1),
+			})
+		}
+
+		p := &ps[len(ps)-1]
+
+		p.Avg = (p.Avg*float64(p.Count) + v) / float64(p.Count+1)
+		p.Count++
+		p.Min = math.Min(p.Min, v)
+		p.Max = math.Max(p.Max, v)
+	}
+
+	return ps
+}
+
+func (cr *Core) getCameraHistory(c echo.Context) error {
+	cam, err := cr.dbs.Camera(c.Param("camera-id"))
+	if err != nil {
+		if errors.Is(err, entity.ErrCameraNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound,
+				"camera not found")
+		}
+		return fmt.Errorf("get camera from DB storage: %w", err)
+	}
+
+	to := time.Now()
+
+	if toStr := c.QueryParam("to"); toStr != "" {
+		to, err = time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				fmt.Errorf("parse to: %w", err))
+		}
+	}
+
+	from := to.Add(-defaultHistoryPeriod)
+
+	if fromStr := c.QueryParam("from"); fromStr != "" {
+		from, err = time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				fmt.Errorf("parse from: %w", err))
+		}
+	}
+
+	var step time.Duration
+
+	if stepStr := c.QueryParam("step"); stepStr != "" {
+		step, err = time.ParseDuration(stepStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				fmt.Errorf("parse step: %w", err))
+		}
+		if step < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				"step is negative")
+		}
+	}
+
+	rs, err := cr.dbs.History(entity.Task{CameraID: cam.ID}.HistoryKey(),
+		from, to)
+	if err != nil {
+		return fmt.Errorf("get history from DB storage: %w", err)
+	}
+
+	if taskType := c.QueryParam("type"); taskType != "" {
+		var frs []entity.HistoryRecord
+		for _, r := range rs {
+			if r.TaskType == taskType {
+				frs = append(frs, r)
+			}
+		}
+		rs = frs
+	}
+
+	metric := c.QueryParam("metric")
+	if metric == "" {
+		if rs == nil {
+			rs = []entity.HistoryRecord{}
+		}
+		return c.JSON(http.StatusOK, rs)
+	}
+
+	return c.JSON(http.StatusOK, downsampleHistory(rs, metric, from, step))
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"time"
+
+	"github.com/dimuls/camtester/entity"
+)
+
+func sameSubtask(a, b *int) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+// attemptsCount returns count of attempts of task or subtask of complex
+// task.
+func attemptsCount(t entity.Task, subtaskIndex *int) (n int) {
+	for _, a := range t.Attempts {
+		if sameSubtask(a.SubtaskIndex, subtaskIndex) {
+			n++
+		}
+	}
+	return
+}
+
+// lastAttempt returns last attempt of task or subtask of complex task.
+func lastAttempt(t entity.Task, subtaskIndex *int) *entity.TaskAttempt {
+	for i := len(t.Attempts) - 1; i >= 0; i-- {
+		if sameSubtask(t.Attempts[i].SubtaskIndex, subtaskIndex) {
+			return &t.Attempts[i]
+		}
+	}
+	return nil
+}
+
+// retryTask handles failed result of task or subtask of complex task. If
+// result attempt is superseded by another attempt or retry policy allows to
+// retry it, result is stored in its attempt instead of task and true is
+// returned. Returned attempts should be published.
+func retryTask(t *entity.Task, tr entity.TaskResult) (
+	[]entity.TaskAttempt, bool) {
+
+	if tr.Ok || tr.AttemptID == "" || t.Cancelled {
+		return nil, false
+	}
+
+	a := t.Attempt(tr.AttemptID)
+	if a == nil {
+		return nil, false
+	}
+
+	if lastAttempt(*t, tr.SubtaskIndex).ID != a.ID {
+		a.Result = &tr
+		return nil, true
+	}
+
+	rt := resultTask(*t, tr)
+	if rt.Retry == nil || !rt.Retry.Retryable(tr.Reason) {
+		return nil, false
+	}
+
+	n := attemptsCount(*t, tr.SubtaskIndex)
+	if n >= rt.Retry.MaxAttempts {
+		return nil, false
+	}
+
+	a.Result = &tr
+
+	publishAt := time.Now().Add(rt.Retry.Backoff(n))
+
+	return []entity.TaskAttempt{
+		newAttempt(t, tr.SubtaskIndex, publishAt),
+	}, true
+}
@@ -0,0 +1,186 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+
+	"github.com/dimuls/camtester/entity"
+)
+
+const schedulerTickPeriod = time.Second
+
+// intervalSchedule fires every interval starting from start. Fire times are
+// computed from schedule creation time, so all core replicas get the same
+// fire times and can lock them in DB storage.
+type intervalSchedule struct {
+	start    time.Time
+	interval time.Duration
+}
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	if t.Before(s.start) {
+		return s.start
+	}
+	n := t.Sub(s.start)/s.interval + 1
+	return s.start.Add(n * s.interval)
+}
+
+func parseSchedule(s entity.Schedule) (cron.Schedule, error) {
+	if s.Cron != "" {
+		cs, err := cron.ParseStandard(s.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("parse cron: %w", err)
+		}
+		return cs, nil
+	}
+	return intervalSchedule{
+		start:    s.CreatedAt,
+		interval: time.Duration(s.IntervalSec) * time.Second,
+	}, nil
+}
+
+func (cr *Core) runScheduler() {
+	defer cr.wg.Done()
+
+	t := time.NewTicker(schedulerTickPeriod)
+	defer t.Stop()
+
+	from := time.Now()
+
+	for {
+		select {
+		case <-cr.stop:
+			return
+		case to := <-t.C:
+			cr.fireSchedules(from, to)
+			from = to
+		}
+	}
+}
+
+// fireSchedules creates tasks of schedules which fire time is in (from, to].
+// Schedule fires once even if several fire times are in range.
+func (cr *Core) fireSchedules(from, to time.Time) {
+	ss, err := cr.dbs.Schedules()
+	if err != nil {
+		cr.log.WithError(err).Error("failed to get schedules from DB storage")
+		return
+	}
+
+	for _, s := range ss {
+		log := cr.log.WithField("schedule_id", s.ID)
+
+		sp, err := parseSchedule(s)
+		if err != nil {
+			log.WithError(err).Error("failed to parse schedule")
+			continue
+		}
+
+		fireTime := sp.Next(from)
+		if fireTime.After(to) {
+			continue
+		}
+
+		locked, err := cr.dbs.LockSchedule(s.ID, fireTime)
+		if err != nil {
+			log.WithError(err).Error("failed to lock schedule")
+			continue
+		}
+		if !locked {
+			continue
+		}
+
+		taskID, err := cr.createTask(s.Task)
+		if err != nil {
+			log.WithError(err).Error("failed to create scheduled task")
+			continue
+		}
+
+		log.WithFields(logrus.Fields{
+			"task_id":   taskID,
+			"fire_time": fireTime,
+		}).Info("scheduled task created")
+	}
+}
+
+func (cr *Core) postSchedules(c echo.Context) error {
+	var s entity.Schedule
+
+	err := json.NewDecoder(c.Request().Body).Decode(&s)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Errorf("JSON decode schedule: %w", err))
+	}
+
+	err = s.Validate()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Errorf("schedule validation: %w", err))
+	}
+
+	err = validateTaskTypes(s.Task)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Errorf("schedule validation: task: %w", err))
+	}
+
+	s.ID = uuid.New().String()
+	s.CreatedAt = time.Now().Truncate(time.Second)
+
+	_, err = parseSchedule(s)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Errorf("schedule validation: %w", err))
+	}
+
+	err = cr.dbs.SetSchedule(s)
+	if err != nil {
+		return fmt.Errorf("set schedule in DB storage: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, s.ID)
+}
+
+func (cr *Core) getSchedules(c echo.Context) error {
+	ss, err := cr.dbs.Schedules()
+	if err != nil {
+		return fmt.Errorf("get schedules from DB storage: %w", err)
+	}
+	for i := range ss {
+		ss[i].Task = hideCallbackSecret(ss[i].Task)
+	}
+	return c.JSON(http.StatusOK, ss)
+}
+
+func (cr *Core) getSchedule(c echo.Context) error {
+	s, err := cr.dbs.Schedule(c.Param("schedule-id"))
+	if err != nil {
+		if errors.Is(err, entity.ErrScheduleNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound,
+				"schedule not found")
+		}
+		return fmt.Errorf("get schedule from DB storage: %w", err)
+	}
+	s.Task = hideCallbackSecret(s.Task)
+	return c.JSON(http.StatusOK, s)
+}
+
+func (cr *Core) deleteSchedule(c echo.Context) error {
+	err := cr.dbs.DeleteSchedule(c.Param("schedule-id"))
+	if err != nil {
+		if errors.Is(err, entity.ErrScheduleNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound,
+				"schedule not found")
+		}
+		return fmt.Errorf("delete schedule from DB storage: %w", err)
+	}
+	return c.NoContent(http.StatusOK)
+}
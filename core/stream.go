@@ -0,0 +1,241 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo"
+
+	"github.com/dimuls/camtester/entity"
+)
+
+const (
+	streamBufferSize      = 100
+	streamKeepAlivePeriod = 15 * time.Second
+	streamWriteTimeout    = 10 * time.Second
+)
+
+type taskResultEvent struct {
+	TaskID      string            `json:"task_id"`
+	TaskType    string            `json:"task_type"`
+	SubtaskType string            `json:"subtask_type,omitempty"`
+	GeoLocation string            `json:"geo_location"`
+	Result      entity.TaskResult `json:"result"`
+}
+
+func newTaskResultEvent(t entity.Task, tr entity.TaskResult) taskResultEvent {
+	e := taskResultEvent{
+		TaskID:      t.ID,
+		TaskType:    t.Type,
+		GeoLocation: t.GeoLocation,
+		Result:      tr,
+	}
+	if t.Type == entity.ComplextTaskType {
+		e.SubtaskType = resultTask(t, tr).Type
+	}
+	return e
+}
+
+type taskResultFilter struct {
+	taskID      string
+	taskType    string
+	geoLocation string
+}
+
+func taskResultFilterFromQuery(c echo.Context) taskResultFilter {
+	return taskResultFilter{
+		taskID:      c.QueryParam("task_id"),
+		taskType:    c.QueryParam("type"),
+		geoLocation: c.QueryParam("geo_location"),
+	}
+}
+
+func (f taskResultFilter) match(e taskResultEvent) bool {
+	if f.taskID != "" && f.taskID != e.TaskID {
+		return false
+	}
+	if f.taskType != "" && f.taskType != e.TaskType &&
+		f.taskType != e.SubtaskType {
+		return false
+	}
+	if f.geoLocation != "" && f.geoLocation != e.GeoLocation {
+		return false
+	}
+	return true
+}
+
+// resultBroker fans out handled task results to stream subscribers. Slow
+// subscribers lose events instead of blocking task result handling.
+type resultBroker struct {
+	subs map[chan taskResultEvent]taskResultFilter
+	mx   sync.RWMutex
+}
+
+func newResultBroker() *resultBroker {
+	return &resultBroker{
+		subs: map[chan taskResultEvent]taskResultFilter{},
+	}
+}
+
+func (b *resultBroker) subscribe(f taskResultFilter) chan taskResultEvent {
+	ch := make(chan taskResultEvent, streamBufferSize)
+	b.mx.Lock()
+	b.subs[ch] = f
+	b.mx.Unlock()
+	return ch
+}
+
+func (b *resultBroker) unsubscribe(ch chan taskResultEvent) {
+	b.mx.Lock()
+	delete(b.subs, ch)
+	b.mx.Unlock()
+}
+
+func (b *resultBroker) publish(e taskResultEvent) {
+	b.mx.RLock()
+	defer b.mx.RUnlock()
+
+	for ch, f := range b.subs {
+		if !f.match(e) {
+			continue
+		}
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+func (cr *Core) getTasksStream(c echo.Context) error {
+	ch := cr.resultBroker.subscribe(taskResultFilterFromQuery(c))
+	defer cr.resultBroker.unsubscribe(ch)
+
+	res := c.Response()
+
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("Connection", "keep-alive")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlivePeriod)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-cr.stop:
+			return nil
+		case <-c.Request().Context().Done():
+			return nil
+		case <-keepAlive.C:
+			_, err := fmt.Fprint(res, ": keep-alive\n\n")
+			if err != nil {
+				return nil
+			}
+			res.Flush()
+		case e := <-ch:
+			eJSON, err := json.Marshal(e)
+			if err != nil {
+				return fmt.Errorf("JSON marshal task result event: %w", err)
+			}
+			_, err = fmt.Fprintf(res, "event: task_result\ndata: %s\n\n",
+				eJSON)
+			if err != nil {
+				return nil
+			}
+			res.Flush()
+		}
+	}
+}
+
+// checkWSOrigin allows websocket requests without origin, which are not
+// sent by browsers, and requests with origin of request host or one of
+// allowed origins.
+func (cr *Core) checkWSOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	for _, ao := range cr.wsAllowedOrigins {
+		if strings.EqualFold(origin, ao) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (cr *Core) getTasksStreamWS(c echo.Context) error {
+	wsUpgrader := websocket.Upgrader{CheckOrigin: cr.checkWSOrigin}
+
+	conn, err := wsUpgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		return fmt.Errorf("upgrade to websocket: %w", err)
+	}
+
+	defer func() {
+		err = conn.Close()
+		if err != nil {
+			cr.log.WithError(err).Error("failed to close websocket")
+		}
+	}()
+
+	ch := cr.resultBroker.subscribe(taskResultFilterFromQuery(c))
+	defer cr.resultBroker.unsubscribe(ch)
+
+	// Client messages are ignored, reading is needed only to handle
+	// control frames and connection close.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			_, _, err := conn.NextReader()
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	keepAlive := time.NewTicker(streamKeepAlivePeriod)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-cr.stop:
+			return nil
+		case <-closed:
+			return nil
+		case <-keepAlive.C:
+			err = conn.WriteControl(websocket.PingMessage, nil,
+				time.Now().Add(streamWriteTimeout))
+			if err != nil {
+				return nil
+			}
+		case e := <-ch:
+			err = conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if err != nil {
+				return nil
+			}
+			err = conn.WriteJSON(e)
+			if err != nil {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dimuls/camtester/entity"
+)
+
+const (
+	sweepPeriod = 10 * time.Second
+
+	// pendingSweepPeriod is period of pending attempts publishing, so it's
+	// accuracy of retry backoff.
+	pendingSweepPeriod = time.Second
+
+	// pendingAttemptGrace is how long pending attempt which is not found in
+	// task is checked again, because its entry is added before task is
+	// stored.
+	pendingAttemptGrace = time.Minute
+
+	defaultTaskTimeout = 5 * time.Minute
+
+	// timeoutMaxAttempts limits attempts of task or subtask republished by
+	// timeout policy.
+	timeoutMaxAttempts = 3
+)
+
+// runSweeper periodically publishes pending attempts which publish time is
+// come and handles attempts which deadline is exceeded without result:
+// writes failed result or republishes task.
+func (cr *Core) runSweeper() {
+	defer cr.wg.Done()
+
+	t := time.NewTicker(sweepPeriod)
+	defer t.Stop()
+
+	pt := time.NewTicker(pendingSweepPeriod)
+	defer pt.Stop()
+
+	for {
+		select {
+		case <-cr.stop:
+			return
+		case now := <-pt.C:
+			cr.publishPending(now)
+		case now := <-t.C:
+			cr.sweep(now)
+		}
+	}
+}
+
+func (cr *Core) publishPending(now time.Time) {
+	pas, err := cr.dbs.DuePendingAttempts(now)
+	if err != nil {
+		cr.log.WithError(err).Error(
+			"failed to get due pending attempts from DB storage")
+		return
+	}
+
+	for _, pa := range pas {
+		log := cr.log.WithField("task_id", pa.TaskID).
+			WithField("attempt_id", pa.AttemptID)
+
+		t, err := cr.dbs.Task(pa.TaskID)
+		if err != nil {
+			if errors.Is(err, entity.ErrTaskNotFound) {
+				if now.Sub(pa.PublishAt) > pendingAttemptGrace {
+					cr.dropPendingAttempt(pa)
+				}
+				continue
+			}
+			log.WithError(err).Error("failed to get task from DB storage")
+			continue
+		}
+
+		a := t.Attempt(pa.AttemptID)
+		if a == nil {
+			if now.Sub(pa.PublishAt) > pendingAttemptGrace {
+				cr.dropPendingAttempt(pa)
+			}
+			continue
+		}
+
+		if t.Cancelled || t.Finished() || a.Result != nil ||
+			isDuplicateResult(t, entity.TaskResult{
+				SubtaskIndex: a.SubtaskIndex,
+			}) {
+			log.Info("pending attempt is not needed, dropping it")
+			cr.dropPendingAttempt(pa)
+			continue
+		}
+
+		err = cr.claimAndPublishAttempt(t, *a)
+		if err != nil {
+			log.WithError(err).Error("failed to publish pending attempt")
+		}
+	}
+}
+
+func (cr *Core) dropPendingAttempt(pa entity.PendingAttempt) {
+	_, err := cr.dbs.RemovePendingAttempt(pa)
+	if err != nil {
+		cr.log.WithError(err).WithField("task_id", pa.TaskID).
+			WithField("attempt_id", pa.AttemptID).
+			Error("failed to remove pending attempt from DB storage")
+	}
+}
+
+func (cr *Core) sweep(now time.Time) {
+	ds, err := cr.dbs.ExpiredAttemptDeadlines(now)
+	if err != nil {
+		cr.log.WithError(err).Error(
+			"failed to get expired attempt deadlines from DB storage")
+		return
+	}
+
+	for _, d := range ds {
+		log := cr.log.WithField("task_id", d.TaskID).
+			WithField("attempt_id", d.AttemptID)
+
+		claimed, err := cr.dbs.RemoveAttemptDeadline(d)
+		if err != nil {
+			log.WithError(err).Error(
+				"failed to remove attempt deadline from DB storage")
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		err = cr.handleAttemptTimeout(d)
+		if err != nil {
+			log.WithError(err).Error("failed to handle attempt timeout")
+
+			// Return deadline back to handle it on next sweep.
+			err = cr.dbs.AddAttemptDeadline(d)
+			if err != nil {
+				log.WithError(err).Error(
+					"failed to add attempt deadline to DB storage")
+			}
+		}
+	}
+}
+
+func (cr *Core) handleAttemptTimeout(d entity.AttemptDeadline) error {
+	log := cr.log.WithField("task_id", d.TaskID).
+		WithField("attempt_id", d.AttemptID)
+
+	var (
+		fail bool
+		as   []entity.TaskAttempt
+	)
+
+	t, err := cr.updateTask(d.TaskID, func(t *entity.Task) error {
+		fail = false
+		as = nil
+
+		a := t.Attempt(d.AttemptID)
+		if a == nil || t.Cancelled || t.Finished() {
+			return errSkipTaskUpdate
+		}
+
+		if isDuplicateResult(*t, entity.TaskResult{
+			SubtaskIndex: a.SubtaskIndex,
+		}) {
+			return errSkipTaskUpdate
+		}
+
+		if t.TimeoutPolicy != entity.TimeoutPolicyRepublish ||
+			attemptsCount(*t, a.SubtaskIndex) >= timeoutMaxAttempts {
+			fail = true
+			return errSkipTaskUpdate
+		}
+
+		as = append(as, newAttempt(t, a.SubtaskIndex, time.Now()))
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, errSkipTaskUpdate) {
+		if errors.Is(err, entity.ErrTaskNotFound) {
+			return nil
+		}
+		return fmt.Errorf("update task: %w", err)
+	}
+
+	if len(as) != 0 {
+		log.Info("attempt timed out, republishing task")
+		cr.publishTask(t, as)
+		return nil
+	}
+
+	if !fail {
+		return nil
+	}
+
+	log.Info("attempt timed out, writing failed result")
+
+	payload, err := json.Marshal(entity.NewTaskError(entity.TimeoutReason,
+		entity.ErrorStageCore, "task attempt deadline exceeded"))
+	if err != nil {
+		return fmt.Errorf("JSON marshal timeout payload: %w", err)
+	}
+
+	return cr.HandleTaskResult(entity.TaskResult{
+		TaskID:    d.TaskID,
+		AttemptID: d.AttemptID,
+		Time:      time.Now(),
+		Reason:    entity.TimeoutReason,
+		Payload:   payload,
+	})
+}
@@ -0,0 +1,201 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo"
+
+	"github.com/dimuls/camtester/entity"
+)
+
+const tamperTaskType = "tamper"
+
+// maxReferenceSize is max size of uploaded camera reference image.
+const maxReferenceSize = 4 << 20
+
+// referenceContentTypes are allowed content types of camera reference image.
+var referenceContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+}
+
+type tamperPayload struct {
+	URI              string `json:"uri"`
+	ReferenceID      string `json:"reference_id,omitempty"`
+	CaptureReference bool   `json:"capture_reference,omitempty"`
+}
+
+// tamperPayload returns tamper task payload of camera with artifact ID of
+// camera reference image.
+func (cr *Core) tamperPayload(c entity.Camera) (json.RawMessage, error) {
+	refID, err := cr.dbs.CameraReference(c.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get camera reference from DB storage: %w",
+			err)
+	}
+
+	return json.Marshal(tamperPayload{
+		URI:         c.RTSPURI,
+		ReferenceID: refID,
+	})
+}
+
+// storeCapturedReference sets reference image captured by successful tamper
+// task as camera reference. Reference is set only if task explicitly
+// requested reference capture.
+func (cr *Core) storeCapturedReference(t entity.Task, tr entity.TaskResult) {
+	st := resultTask(t, tr)
+
+	if st.Type != tamperTaskType || st.CameraID == "" || !tr.Ok {
+		return
+	}
+
+	log := cr.log.WithField("task_id", t.ID)
+
+	var p tamperPayload
+
+	err := json.Unmarshal(st.Payload, &p)
+	if err != nil {
+		log.WithError(err).Error("failed to JSON unmarshal tamper payload")
+		return
+	}
+
+	if !p.CaptureReference {
+		return
+	}
+
+	var res struct {
+		CapturedReferenceID string `json:"captured_reference_id"`
+	}
+
+	err = json.Unmarshal(tr.Payload, &res)
+	if err != nil {
+		log.WithError(err).Error("failed to JSON unmarshal tamper result")
+		return
+	}
+
+	if res.CapturedReferenceID == "" {
+		return
+	}
+
+	err = cr.dbs.SetCameraReference(st.CameraID, res.CapturedReferenceID)
+	if err != nil {
+		log.WithError(err).Error("failed to set camera reference in DB storage")
+		return
+	}
+
+	log.WithField("camera_id", st.CameraID).Info("camera reference refreshed")
+}
+
+func (cr *Core) getCameraReference(c echo.Context) error {
+	refID, err := cr.dbs.CameraReference(c.Param("camera-id"))
+	if err != nil {
+		if errors.Is(err, entity.ErrCameraReferenceNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound,
+				"camera reference not found")
+		}
+		return fmt.Errorf("get camera reference from DB storage: %w", err)
+	}
+
+	a, err := cr.artifacts.Artifact(refID)
+	if err != nil {
+		if errors.Is(err, entity.ErrArtifactNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound,
+				"camera reference not found")
+		}
+		return fmt.Errorf("get camera reference from artifact store: %w", err)
+	}
+
+	return c.Blob(http.StatusOK, a.ContentType, a.Data)
+}
+
+func (cr *Core) putCameraReference(c echo.Context) error {
+	cameraID := c.Param("camera-id")
+
+	_, err := cr.dbs.Camera(cameraID)
+	if err != nil {
+		if errors.Is(err, entity.ErrCameraNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound,
+				"camera not found")
+		}
+		return fmt.Errorf("get camera from DB storage: %w", err)
+	}
+
+	ref, err := ioutil.ReadAll(io.LimitReader(c.Request().Body,
+		maxReferenceSize+1))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Errorf("read camera reference: %w", err))
+	}
+
+	if len(ref) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"camera reference is empty")
+	}
+
+	if len(ref) > maxReferenceSize {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge,
+			"camera reference is too large")
+	}
+
+	a := entity.Artifact{
+		ID:          uuid.New().String(),
+		ContentType: http.DetectContentType(ref),
+		Data:        ref,
+	}
+
+	if !referenceContentTypes[a.ContentType] {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"camera reference is not JPEG or PNG image")
+	}
+
+	err = cr.artifacts.SetArtifact(a)
+	if err != nil {
+		return fmt.Errorf("set camera reference in artifact store: %w", err)
+	}
+
+	err = cr.dbs.SetCameraReference(cameraID, a.ID)
+	if err != nil {
+		return fmt.Errorf("set camera reference in DB storage: %w", err)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// postCameraReferenceRefresh creates tamper task which captures new camera
+// reference image. Reference is set when task is successfully finished.
+func (cr *Core) postCameraReferenceRefresh(c echo.Context) error {
+	cam, err := cr.dbs.Camera(c.Param("camera-id"))
+	if err != nil {
+		if errors.Is(err, entity.ErrCameraNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound,
+				"camera not found")
+		}
+		return fmt.Errorf("get camera from DB storage: %w", err)
+	}
+
+	p, err := json.Marshal(tamperPayload{
+		URI:              cam.RTSPURI,
+		CaptureReference: true,
+	})
+	if err != nil {
+		return fmt.Errorf("JSON marshal tamper payload: %w", err)
+	}
+
+	id, err := cr.createTask(entity.Task{
+		Type:     tamperTaskType,
+		CameraID: cam.ID,
+		Payload:  p,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, id)
+}
@@ -0,0 +1,106 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dimuls/camtester/entity"
+)
+
+// taskType is task type handled by workers.
+type taskType struct {
+	// timeout is default timeout of task attempt.
+	timeout time.Duration
+
+	// cameraPayload makes task payload from referenced camera.
+	cameraPayload func(cr *Core, c entity.Camera) (json.RawMessage, error)
+}
+
+// taskTypes are known task types. Tasks of other types are not created.
+var taskTypes = map[string]taskType{
+	"ping": {
+		timeout:       time.Minute,
+		cameraPayload: hostPayload,
+	},
+	"check": {
+		timeout:       2 * time.Minute,
+		cameraPayload: stringURIPayload,
+	},
+	"probe": {
+		timeout:       3 * time.Minute,
+		cameraPayload: stringURIPayload,
+	},
+	"conformance": {
+		timeout:       2 * time.Minute,
+		cameraPayload: profilePayload,
+	},
+	"timing": {
+		timeout:       2 * time.Minute,
+		cameraPayload: uriPayload,
+	},
+	"snapshot": {
+		timeout:       time.Minute,
+		cameraPayload: uriPayload,
+	},
+	tamperTaskType: {
+		timeout:       time.Minute,
+		cameraPayload: (*Core).tamperPayload,
+	},
+}
+
+// validateTaskTypes checks that task and its subtasks are of known types.
+func validateTaskTypes(t entity.Task) error {
+	if t.Type != entity.ComplextTaskType {
+		if _, ok := taskTypes[t.Type]; !ok {
+			return fmt.Errorf("%w: %s", entity.ErrUnknownTaskType, t.Type)
+		}
+		return nil
+	}
+	for i, st := range t.Payloads {
+		if _, ok := taskTypes[st.Type]; !ok {
+			return fmt.Errorf("subtask #%d: %w: %s", i,
+				entity.ErrUnknownTaskType, st.Type)
+		}
+	}
+	return nil
+}
+
+func (cr *Core) cameraPayload(taskType string, c entity.Camera) (
+	json.RawMessage, error) {
+
+	tt, ok := taskTypes[taskType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", entity.ErrUnknownTaskType, taskType)
+	}
+	return tt.cameraPayload(cr, c)
+}
+
+func hostPayload(_ *Core, c entity.Camera) (json.RawMessage, error) {
+	return json.Marshal(c.PingHost())
+}
+
+func stringURIPayload(_ *Core, c entity.Camera) (json.RawMessage, error) {
+	return json.Marshal(c.RTSPURI)
+}
+
+func uriPayload(_ *Core, c entity.Camera) (json.RawMessage, error) {
+	return json.Marshal(struct {
+		URI string `json:"uri"`
+	}{
+		URI: c.RTSPURI,
+	})
+}
+
+func profilePayload(_ *Core, c entity.Camera) (json.RawMessage, error) {
+	if c.Profile == nil {
+		return nil, entity.ErrCameraProfileNotFound
+	}
+	return json.Marshal(struct {
+		URI     string                `json:"uri"`
+		Profile *entity.StreamProfile `json:"profile"`
+	}{
+		URI:     c.RTSPURI,
+		Profile: c.Profile,
+	})
+}
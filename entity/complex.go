@@ -0,0 +1,162 @@
+package entity
+
+import "fmt"
+
+// Complex task subtasks run sequentially by default: every subtask depends
+// on previous one. If task is parallel or any subtask has depends_on, only
+// explicit dependencies are used, so subtasks without dependencies run at
+// once. Dispatching stops on first failed subtask unless task should
+// continue on failure.
+
+func (t Task) dagMode() bool {
+	if t.Parallel {
+		return true
+	}
+	for _, st := range t.Payloads {
+		if len(st.DependsOn) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// SubtaskDependencies returns indexes of subtasks which subtask i depends on.
+func (t Task) SubtaskDependencies(i int) []int {
+	if t.dagMode() {
+		return t.Payloads[i].DependsOn
+	}
+	if i == 0 {
+		return nil
+	}
+	return []int{i - 1}
+}
+
+// SubtaskResult returns result of subtask i or nil if subtask has no
+// result yet. Results without subtask index are correlated by order.
+func (t Task) SubtaskResult(i int) *TaskResult {
+	for j, tr := range t.Results {
+		if (tr.SubtaskIndex == nil && j == i) ||
+			(tr.SubtaskIndex != nil && *tr.SubtaskIndex == i) {
+			return &t.Results[j]
+		}
+	}
+	return nil
+}
+
+// Subtask returns subtask i as it is published: with task ID, geo location,
+// camera, timeout and retry policy inherited from task.
+func (t Task) Subtask(i int) Task {
+	st := t.Payloads[i]
+	st.ID = t.ID
+	st.GeoLocation = t.GeoLocation
+	if st.CameraID == "" {
+		st.CameraID = t.CameraID
+	}
+	if st.TimeoutSec == 0 {
+		st.TimeoutSec = t.TimeoutSec
+	}
+	if st.Retry == nil {
+		st.Retry = t.Retry
+	}
+	st.DependsOn = nil
+	st.Results = nil
+	st.Result = nil
+	st.Attempts = nil
+	st.SubtaskIndex = &i
+	return st
+}
+
+func (t Task) subtaskResultsCount() (n int) {
+	for i := range t.Payloads {
+		if t.SubtaskResult(i) != nil {
+			n++
+		}
+	}
+	return
+}
+
+func (t Task) hasFailedSubtask() bool {
+	for _, tr := range t.Results {
+		if !tr.Ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (t Task) dispatched(i int) bool {
+	for _, a := range t.Attempts {
+		if a.SubtaskIndex != nil && *a.SubtaskIndex == i {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadySubtasks returns indexes of not dispatched subtasks which
+// dependencies have results.
+func (t Task) ReadySubtasks() (ready []int) {
+	if t.Cancelled || t.Finished() {
+		return nil
+	}
+
+	for i := range t.Payloads {
+		if t.dispatched(i) || t.SubtaskResult(i) != nil {
+			continue
+		}
+
+		isReady := true
+
+		for _, d := range t.SubtaskDependencies(i) {
+			if t.SubtaskResult(d) == nil {
+				isReady = false
+				break
+			}
+		}
+
+		if isReady {
+			ready = append(ready, i)
+		}
+	}
+
+	return
+}
+
+func (t Task) checkSubtaskCycles() error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make([]int, len(t.Payloads))
+
+	var visit func(i int) error
+
+	visit = func(i int) error {
+		switch state[i] {
+		case visiting:
+			return fmt.Errorf("subtask #%d: dependency cycle", i)
+		case visited:
+			return nil
+		}
+		state[i] = visiting
+		for _, d := range t.Payloads[i].DependsOn {
+			err := visit(d)
+			if err != nil {
+				return err
+			}
+		}
+		state[i] = visited
+		return nil
+	}
+
+	for i := range t.Payloads {
+		err := visit(i)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -4,21 +4,71 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 	"time"
 )
 
 const ComplextTaskType = "complex"
 
+const (
+	TimeoutPolicyFail      = "fail"
+	TimeoutPolicyRepublish = "republish"
+)
+
+// TimeoutReason is reason of failed result which core writes when task
+// attempt deadline is exceeded.
+const TimeoutReason = ErrorCodeTimeout
+
+const defaultMaxRetryBackoff = time.Hour
+
+const (
+	TaskStatusPending   = "pending"
+	TaskStatusFinished  = "finished"
+	TaskStatusCancelled = "cancelled"
+)
+
 type Task struct {
 	ID          string `json:"id"`
 	Type        string `json:"type"`
 	GeoLocation string `json:"geo_location"`
+	CameraID    string `json:"camera_id,omitempty"`
 
 	Payload json.RawMessage `json:"payload,omitempty"`
 	Result  *TaskResult     `json:"result,omitempty"`
 
 	Payloads []Task       `json:"payloads,omitempty"`
 	Results  []TaskResult `json:"results,omitempty"`
+
+	// Complex task execution options, see complex.go.
+	Parallel          bool  `json:"parallel,omitempty"`
+	ContinueOnFailure bool  `json:"continue_on_failure,omitempty"`
+	DependsOn         []int `json:"depends_on,omitempty"`
+
+	// TimeoutSec overrides default timeout of task type. TimeoutPolicy
+	// defines what core does with timed out attempt.
+	TimeoutSec    int    `json:"timeout_sec,omitempty"`
+	TimeoutPolicy string `json:"timeout_policy,omitempty"`
+
+	// Retry defines republishing of failed task or subtasks of complex
+	// task. Subtask retry policy overrides task one.
+	Retry *RetryPolicy `json:"retry,omitempty"`
+
+	// Attempts are publications of task or subtasks of complex task.
+	Attempts []TaskAttempt `json:"attempts,omitempty"`
+
+	// SubtaskIndex is set in published subtask of complex task. AttemptID
+	// is set in every published task. Both are copied to task result.
+	SubtaskIndex *int       `json:"subtask_index,omitempty"`
+	AttemptID    string     `json:"attempt_id,omitempty"`
+	Deadline     *time.Time `json:"deadline,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	Cancelled bool      `json:"cancelled,omitempty"`
+
+	// Version is incremented on every task update in DB storage.
+	Version int `json:"version"`
+
+	Callback *Callback `json:"callback,omitempty"`
 }
 
 func (t Task) Validate() error {
@@ -26,7 +76,7 @@ func (t Task) Validate() error {
 		return errors.New("type is empty")
 	}
 
-	if t.GeoLocation == "" {
+	if t.GeoLocation == "" && t.CameraID == "" {
 		return errors.New("geo_location is empty")
 	}
 
@@ -41,19 +91,145 @@ func (t Task) Validate() error {
 			if st.Type == ComplextTaskType {
 				return fmt.Errorf("subtask #%d is complex", i)
 			}
-			if len(st.Payload) == 0 {
+			if len(st.Payload) == 0 && st.CameraID == "" &&
+				t.CameraID == "" {
 				return fmt.Errorf("subtask #%d: payload is empty", i)
 			}
+			if st.TimeoutSec < 0 {
+				return fmt.Errorf("subtask #%d: timeout_sec is negative", i)
+			}
+			if st.Retry != nil {
+				err := st.Retry.Validate()
+				if err != nil {
+					return fmt.Errorf("subtask #%d: retry: %w", i, err)
+				}
+			}
+			for _, d := range st.DependsOn {
+				if d < 0 || d >= len(t.Payloads) {
+					return fmt.Errorf(
+						"subtask #%d: depends on unknown subtask #%d", i, d)
+				}
+				if d == i {
+					return fmt.Errorf("subtask #%d: depends on itself", i)
+				}
+			}
+		}
+
+		err := t.checkSubtaskCycles()
+		if err != nil {
+			return err
 		}
 
 	} else {
-		if len(t.Payload) == 0 {
+		if len(t.Payload) == 0 && t.CameraID == "" {
 			return errors.New("payload is empty")
 		}
+		if len(t.DependsOn) != 0 {
+			return errors.New("depends_on is set in not complex task")
+		}
+	}
+
+	if t.TimeoutSec < 0 {
+		return errors.New("timeout_sec is negative")
+	}
+
+	switch t.TimeoutPolicy {
+	case "", TimeoutPolicyFail, TimeoutPolicyRepublish:
+	default:
+		return errors.New("unknown timeout_policy")
+	}
+
+	if t.Retry != nil {
+		err := t.Retry.Validate()
+		if err != nil {
+			return fmt.Errorf("retry: %w", err)
+		}
+	}
+
+	if t.Callback != nil {
+		err := t.Callback.Validate()
+		if err != nil {
+			return fmt.Errorf("callback: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Finished returns true if task will not get any more results: simple task
+// has result, complex task has results for all subtasks or has failed
+// subtask and should not continue on failure.
+func (t Task) Finished() bool {
+	if t.Type == ComplextTaskType {
+		return t.subtaskResultsCount() == len(t.Payloads) ||
+			(t.hasFailedSubtask() && !t.ContinueOnFailure)
+	}
+	return t.Result != nil
+}
+
+// Succeeded returns true if task is finished and all its results are ok.
+func (t Task) Succeeded() bool {
+	if !t.Finished() {
+		return false
+	}
+	if t.Type == ComplextTaskType {
+		return !t.hasFailedSubtask()
+	}
+	return t.Result.Ok
+}
+
+func (t Task) Status() string {
+	switch {
+	case t.Finished():
+		return TaskStatusFinished
+	case t.Cancelled:
+		return TaskStatusCancelled
+	default:
+		return TaskStatusPending
+	}
+}
+
+// PayloadURIs returns URIs of task and its subtasks payloads, which are
+// camera URIs or hosts for all known task types. Payload is either plain
+// string URI or object with uri field.
+func (t Task) PayloadURIs() (uris []string) {
+	var uri string
+	if json.Unmarshal(t.Payload, &uri) != nil {
+		var p struct {
+			URI string `json:"uri"`
+		}
+		if json.Unmarshal(t.Payload, &p) == nil {
+			uri = p.URI
+		}
+	}
+	if uri != "" {
+		uris = append(uris, uri)
+	}
+	for _, st := range t.Payloads {
+		uris = append(uris, st.PayloadURIs()...)
+	}
+	return
+}
+
+// Attempt returns task attempt with given ID or nil if it's not found.
+func (t Task) Attempt(attemptID string) *TaskAttempt {
+	for i, a := range t.Attempts {
+		if a.ID == attemptID {
+			return &t.Attempts[i]
+		}
 	}
 	return nil
 }
 
+// NewResult returns empty result of published task or subtask.
+func (t Task) NewResult() TaskResult {
+	return TaskResult{
+		TaskID:       t.ID,
+		SubtaskIndex: t.SubtaskIndex,
+		AttemptID:    t.AttemptID,
+	}
+}
+
 func (t *Task) MarshalPayload(payload interface{}) (err error) {
 	t.Payload, err = json.Marshal(payload)
 	return
@@ -65,10 +241,105 @@ func (t Task) UnmarshalPayload(v interface{}) (err error) {
 }
 
 type TaskResult struct {
-	TaskID  string          `json:"task_id,omitempty"`
-	Time    time.Time       `json:"time"`
-	Ok      bool            `json:"ok"`
-	Payload json.RawMessage `json:"payload"`
+	TaskID       string          `json:"task_id,omitempty"`
+	SubtaskIndex *int            `json:"subtask_index,omitempty"`
+	AttemptID    string          `json:"attempt_id,omitempty"`
+	Time         time.Time       `json:"time"`
+	Ok           bool            `json:"ok"`
+	Reason       string          `json:"reason,omitempty"`
+	Payload      json.RawMessage `json:"payload"`
+
+	// ArtifactURL is URL of artifact, for example snapshot image, stored
+	// by task handler in artifact store.
+	ArtifactURL string `json:"artifact_url,omitempty"`
+}
+
+type TaskAttempt struct {
+	ID           string    `json:"id"`
+	SubtaskIndex *int      `json:"subtask_index,omitempty"`
+	PublishedAt  time.Time `json:"published_at"`
+	Deadline     time.Time `json:"deadline"`
+
+	// Result is failed result of attempt which is retried or superseded by
+	// another attempt. Final results are stored in task.
+	Result *TaskResult `json:"result,omitempty"`
+}
+
+// RetryPolicy defines how many times failed task is published again and
+// how long core waits before every next attempt. Backoff is doubled after
+// every attempt up to MaxBackoffSec or one hour if it's not set.
+type RetryPolicy struct {
+	MaxAttempts   int `json:"max_attempts"`
+	BackoffSec    int `json:"backoff_sec,omitempty"`
+	MaxBackoffSec int `json:"max_backoff_sec,omitempty"`
+
+	// Reasons are failure reasons which are retried. If reasons are empty,
+	// failures with retryable error code or without reason are retried.
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+func (p RetryPolicy) Validate() error {
+	if p.MaxAttempts < 1 {
+		return errors.New("max_attempts is less than 1")
+	}
+
+	if p.BackoffSec < 0 {
+		return errors.New("backoff_sec is negative")
+	}
+
+	if p.MaxBackoffSec < 0 {
+		return errors.New("max_backoff_sec is negative")
+	}
+
+	return nil
+}
+
+// Retryable returns true if failure with given reason should be retried.
+func (p RetryPolicy) Retryable(reason string) bool {
+	if len(p.Reasons) == 0 {
+		return reason == "" || retryableErrorCodes[reason]
+	}
+	for _, r := range p.Reasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff returns delay before publishing attempt which follows given
+// number of failed attempts.
+func (p RetryPolicy) Backoff(failedAttempts int) time.Duration {
+	d := time.Duration(p.BackoffSec) * time.Second
+
+	max := defaultMaxRetryBackoff
+	if p.MaxBackoffSec > 0 {
+		max = time.Duration(p.MaxBackoffSec) * time.Second
+	}
+
+	for i := 1; i < failedAttempts && d < max; i++ {
+		d *= 2
+	}
+
+	if d > max {
+		d = max
+	}
+
+	return d
+}
+
+type AttemptDeadline struct {
+	TaskID    string    `json:"task_id"`
+	AttemptID string    `json:"attempt_id"`
+	Deadline  time.Time `json:"deadline"`
+}
+
+// PendingAttempt is attempt which is published when its publish time comes,
+// for example retry attempt after backoff.
+type PendingAttempt struct {
+	TaskID    string    `json:"task_id"`
+	AttemptID string    `json:"attempt_id"`
+	PublishAt time.Time `json:"publish_at"`
 }
 
 func (t *TaskResult) MarshalPayload(payload interface{}) (err error) {
@@ -81,6 +352,221 @@ func (t TaskResult) UnmarshalPayload(v interface{}) (err error) {
 	return
 }
 
+// HistoryKey returns key of history which task results are appended to:
+// camera ID if task references camera, otherwise payload URI.
+func (t Task) HistoryKey() string {
+	if t.CameraID != "" {
+		return "camera:" + t.CameraID
+	}
+	uris := t.PayloadURIs()
+	if len(uris) == 0 {
+		return ""
+	}
+	return "uri:" + uris[0]
+}
+
+// TaskQuery is tasks search query. Zero fields match any task. Cursor is
+// opaque value returned by previous query.
+type TaskQuery struct {
+	Type        string
+	GeoLocation string
+	Status      string
+	Ok          *bool
+	PayloadURI  string
+	From        time.Time
+	To          time.Time
+	Cursor      string
+	Limit       int
+}
+
+func (q TaskQuery) Match(t Task) bool {
+	if q.Type != "" && q.Type != t.Type {
+		return false
+	}
+	if q.GeoLocation != "" && q.GeoLocation != t.GeoLocation {
+		return false
+	}
+	if q.Status != "" && q.Status != t.Status() {
+		return false
+	}
+	if q.Ok != nil && (!t.Finished() || *q.Ok != t.Succeeded()) {
+		return false
+	}
+	if !q.From.IsZero() && t.CreatedAt.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && t.CreatedAt.After(q.To) {
+		return false
+	}
+	if q.PayloadURI != "" {
+		var found bool
+		for _, uri := range t.PayloadURIs() {
+			if uri == q.PayloadURI {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+type HistoryRecord struct {
+	Time     time.Time       `json:"time"`
+	TaskID   string          `json:"task_id"`
+	TaskType string          `json:"task_type"`
+	Ok       bool            `json:"ok"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+// HistoryPoint is aggregated metric value of history records in time
+// interval starting at point time.
+type HistoryPoint struct {
+	Time  time.Time `json:"time"`
+	Count int       `json:"count"`
+	Min   float64   `json:"min"`
+	Max   float64   `json:"max"`
+	Avg   float64   `json:"avg"`
+}
+
+// StreamProfile is expected camera stream parameters. Zero fields are not
+// checked.
+type StreamProfile struct {
+	Codec               string  `json:"codec,omitempty"`
+	Profile             string  `json:"profile,omitempty"`
+	Width               int     `json:"width,omitempty"`
+	Height              int     `json:"height,omitempty"`
+	FrameRate           float64 `json:"frame_rate,omitempty"`
+	BitrateKbps         int     `json:"bitrate_kbps,omitempty"`
+	KeyframeIntervalSec float64 `json:"keyframe_interval_sec,omitempty"`
+}
+
+// Artifact is file produced by task handler, for example snapshot image.
+type Artifact struct {
+	ID          string `json:"id"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"`
+}
+
+type Camera struct {
+	ID          string         `json:"id"`
+	Name        string         `json:"name"`
+	RTSPURI     string         `json:"rtsp_uri"`
+	Host        string         `json:"host,omitempty"`
+	GeoLocation string         `json:"geo_location"`
+	Tags        []string       `json:"tags,omitempty"`
+	Profile     *StreamProfile `json:"profile,omitempty"`
+}
+
+func (c Camera) Validate() error {
+	if c.Name == "" {
+		return errors.New("name is empty")
+	}
+
+	if c.RTSPURI == "" {
+		return errors.New("rtsp_uri is empty")
+	}
+
+	u, err := url.Parse(c.RTSPURI)
+	if err != nil {
+		return fmt.Errorf("parse rtsp_uri: %w", err)
+	}
+
+	if u.Scheme != "rtsp" && u.Scheme != "rtsps" {
+		return errors.New("rtsp_uri scheme is not rtsp or rtsps")
+	}
+
+	if c.GeoLocation == "" {
+		return errors.New("geo_location is empty")
+	}
+
+	return nil
+}
+
+// PingHost returns camera host or RTSP URI host if camera host is empty.
+func (c Camera) PingHost() string {
+	if c.Host != "" {
+		return c.Host
+	}
+	u, err := url.Parse(c.RTSPURI)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// Callback is HTTP endpoint which receives finished task. If secret is set
+// request body is signed with HMAC-SHA256.
+type Callback struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret,omitempty"`
+}
+
+func (c Callback) Validate() error {
+	if c.URL == "" {
+		return errors.New("url is empty")
+	}
+
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return fmt.Errorf("parse url: %w", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("url scheme is not http or https")
+	}
+
+	return nil
+}
+
+type CallbackDelivery struct {
+	Time       time.Time `json:"time"`
+	Attempt    int       `json:"attempt"`
+	Ok         bool      `json:"ok"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+type Schedule struct {
+	ID          string    `json:"id"`
+	Cron        string    `json:"cron,omitempty"`
+	IntervalSec int       `json:"interval_sec,omitempty"`
+	Task        Task      `json:"task"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (s Schedule) Validate() error {
+	if s.Cron == "" && s.IntervalSec == 0 {
+		return errors.New("cron and interval_sec are empty")
+	}
+
+	if s.Cron != "" && s.IntervalSec != 0 {
+		return errors.New("both cron and interval_sec are set")
+	}
+
+	if s.IntervalSec < 0 {
+		return errors.New("interval_sec is negative")
+	}
+
+	err := s.Task.Validate()
+	if err != nil {
+		return fmt.Errorf("task: %w", err)
+	}
+
+	return nil
+}
+
 var (
-	ErrTaskNotFound = errors.New("task not found")
+	ErrTaskNotFound        = errors.New("task not found")
+	ErrTaskVersionConflict = errors.New("task version conflict")
+	ErrScheduleNotFound    = errors.New("schedule not found")
+	ErrInvalidCursor       = errors.New("invalid cursor")
+	ErrCameraNotFound      = errors.New("camera not found")
+
+	ErrCameraReferenceNotFound = errors.New("camera reference not found")
+	ErrCameraProfileNotFound   = errors.New("camera profile not found")
+	ErrUnknownTaskType         = errors.New("unknown task type")
+	ErrArtifactNotFound        = errors.New("artifact not found")
 )
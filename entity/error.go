@@ -0,0 +1,62 @@
+package entity
+
+// Error codes of failed task results. Code is also set as task result
+// reason, so retry policy reasons are error codes.
+const (
+	ErrorCodeInvalidPayload        = "invalid_payload"
+	ErrorCodeRestreamerUnavailable = "restreamer_unavailable"
+	ErrorCodeAuthFailed            = "auth_failed"
+	ErrorCodeStreamNotFound        = "stream_not_found"
+	ErrorCodeConnectionFailed      = "connection_failed"
+	ErrorCodeTimeout               = "timeout"
+	ErrorCodeDecodeFailed          = "decode_failed"
+	ErrorCodeUnsupportedCodec      = "unsupported_codec"
+	ErrorCodeHostUnreachable       = "host_unreachable"
+	ErrorCodeInternal              = "internal"
+)
+
+// Stages of task handling where error occurred.
+const (
+	ErrorStagePayload    = "payload"
+	ErrorStageRestreamer = "restreamer"
+	ErrorStageRecord     = "record"
+	ErrorStageCheck      = "check"
+	ErrorStageProbe      = "probe"
+	ErrorStagePing       = "ping"
+	ErrorStageGrab       = "grab"
+	ErrorStageCompare    = "compare"
+	ErrorStageStore      = "store"
+	ErrorStageCore       = "core"
+)
+
+var retryableErrorCodes = map[string]bool{
+	ErrorCodeRestreamerUnavailable: true,
+	ErrorCodeConnectionFailed:      true,
+	ErrorCodeTimeout:               true,
+	ErrorCodeDecodeFailed:          true,
+	ErrorCodeHostUnreachable:       true,
+	ErrorCodeInternal:              true,
+}
+
+// TaskError is payload of failed task result. Details are error specific
+// data, for example ping statistics.
+type TaskError struct {
+	Code      string      `json:"code"`
+	Stage     string      `json:"stage"`
+	Message   string      `json:"message"`
+	Retryable bool        `json:"retryable"`
+	Details   interface{} `json:"details,omitempty"`
+}
+
+func NewTaskError(code, stage, message string) TaskError {
+	return TaskError{
+		Code:      code,
+		Stage:     stage,
+		Message:   message,
+		Retryable: retryableErrorCodes[code],
+	}
+}
+
+func (e TaskError) Error() string {
+	return e.Stage + ": " + e.Code + ": " + e.Message
+}
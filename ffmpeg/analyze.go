@@ -0,0 +1,351 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Analysis is result of single pass stream analysis. ErrorLines is count of
+// ffmpeg error output lines, frames are counts of analyzed frames.
+type Analysis struct {
+	ErrorLines  int
+	VideoFrames int
+	AudioFrames int
+}
+
+// FrameHandlers are called for every analyzed frame while ffmpeg is
+// running, so frames are not kept in memory. Video and audio handlers are
+// called from different goroutines. Nil handler skips frames.
+type FrameHandlers struct {
+	Video func(VideoFrame) error
+	Audio func(AudioFrame) error
+}
+
+// Metadata filters print frames metadata to extra file descriptors which
+// are pipes to analyzer: 3 for video and 4 for audio. Loudness filters add
+// metadata to every audio frame, so all audio frames are printed.
+const (
+	videoMetadataFilter = "metadata=mode=print:file=pipe\\:3"
+	audioMetadataFilter = "ametadata=mode=print:file=pipe\\:4"
+)
+
+// AnalyzeStream reads stream once and runs video and audio detection
+// filters on the fly. Frames metadata is parsed and passed to handlers
+// while ffmpeg is running, so no sample file is needed.
+func AnalyzeStream(ctx context.Context, ffmpegPath, uri, transport string,
+	durationSec int, vo VideoOptions, ao AudioOptions, h FrameHandlers) (
+	a Analysis, err error) {
+
+	vr, vw, err := os.Pipe()
+	if err != nil {
+		err = fmt.Errorf("create video metadata pipe: %w", err)
+		return
+	}
+	defer vr.Close()
+
+	ar, aw, err := os.Pipe()
+	if err != nil {
+		vw.Close()
+		err = fmt.Errorf("create audio metadata pipe: %w", err)
+		return
+	}
+	defer ar.Close()
+
+	args := append([]string{"-v", "error", "-nostdin"},
+		inputArgs(uri, transport)...)
+	args = append(args,
+		"-t", strconv.Itoa(durationSec),
+		"-map", "0:v:0?", "-map", "0:a:0?",
+		"-vf", vo.filters()+","+videoMetadataFilter,
+		"-af", ao.filters()+","+audioMetadataFilter,
+		"-f", "null", "-")
+
+	cmd := command(ctx, ffmpegPath, args...)
+
+	var errText bytes.Buffer
+
+	cmd.Stderr = &errText
+	cmd.ExtraFiles = []*os.File{vw, aw}
+
+	err = cmd.Start()
+
+	// Write ends are inherited by ffmpeg, so they are closed here to get
+	// EOF when ffmpeg exits.
+	vw.Close()
+	aw.Close()
+
+	if err != nil {
+		err = fmt.Errorf("start ffmpeg: %w", err)
+		return
+	}
+
+	var (
+		wg         sync.WaitGroup
+		vErr, aErr error
+	)
+
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		vErr = scanMetadata(vr, func(mf metadataFrame) error {
+			vf, err := mf.videoFrame()
+			if err != nil {
+				return err
+			}
+			a.VideoFrames++
+			if h.Video == nil {
+				return nil
+			}
+			return h.Video(vf)
+		})
+	}()
+
+	go func() {
+		defer wg.Done()
+		aErr = scanMetadata(ar, func(mf metadataFrame) error {
+			af, err := mf.audioFrame()
+			if err != nil {
+				return err
+			}
+			a.AudioFrames++
+			if h.Audio == nil {
+				return nil
+			}
+			return h.Audio(af)
+		})
+	}()
+
+	wg.Wait()
+
+	err = cmd.Wait()
+
+	a.ErrorLines = strings.Count(errText.String(), "\n")
+
+	if err != nil {
+		err = runError(ctx, "run ffmpeg", err, errText.String())
+		return
+	}
+
+	if vErr != nil {
+		err = fmt.Errorf("parse video metadata: %w", vErr)
+		return
+	}
+
+	if aErr != nil {
+		err = fmt.Errorf("parse audio metadata: %w", aErr)
+		return
+	}
+
+	return
+}
+
+// metadataFrame is frame metadata printed by metadata filter.
+type metadataFrame struct {
+	time string
+	tags map[string]string
+}
+
+// scanMetadata parses metadata filter print output and calls handle for
+// every frame. Output is read until EOF even after error, so ffmpeg is not
+// blocked on pipe write.
+func scanMetadata(r io.Reader, handle func(metadataFrame) error) (
+	err error) {
+
+	defer func() {
+		if err != nil {
+			io.Copy(ioutil.Discard, r)
+		}
+	}()
+
+	var (
+		s  = bufio.NewScanner(r)
+		mf *metadataFrame
+	)
+
+	for s.Scan() {
+		l := s.Text()
+
+		if strings.HasPrefix(l, "frame:") {
+			if mf != nil {
+				err = handle(*mf)
+				if err != nil {
+					return
+				}
+			}
+
+			mf = &metadataFrame{tags: map[string]string{}}
+
+			for _, f := range strings.Fields(l) {
+				if strings.HasPrefix(f, "pts_time:") {
+					mf.time = strings.TrimPrefix(f, "pts_time:")
+				}
+			}
+
+			continue
+		}
+
+		if mf == nil {
+			continue
+		}
+
+		parts := strings.SplitN(l, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		mf.tags[parts[0]] = parts[1]
+	}
+
+	err = s.Err()
+	if err != nil {
+		return fmt.Errorf("scan: %w", err)
+	}
+
+	if mf != nil {
+		err = handle(*mf)
+	}
+
+	return
+}
+
+func (mf metadataFrame) float(key string) (*float64, error) {
+	v, ok := mf.tags[key]
+	if !ok {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", key, err)
+	}
+	return &f, nil
+}
+
+func (mf metadataFrame) videoFrame() (vf VideoFrame, err error) {
+	vf.Time, err = parseFrameTime(mf.time)
+	if err != nil {
+		return
+	}
+
+	for key, v := range map[string]*float64{
+		"lavfi.signalstats.TOUT":   &vf.Tout,
+		"lavfi.signalstats.YAVG":   &vf.YAvg,
+		"lavfi.signalstats.YMIN":   &vf.YMin,
+		"lavfi.signalstats.YMAX":   &vf.YMax,
+		"lavfi.signalstats.UAVG":   &vf.UAvg,
+		"lavfi.signalstats.VAVG":   &vf.VAvg,
+		"lavfi.signalstats.SATAVG": &vf.SatAvg,
+		"lavfi.signalstats.HUEAVG": &vf.HueAvg,
+		"lavfi.blur":               &vf.Blur,
+	} {
+		var f *float64
+
+		f, err = mf.float(key)
+		if err != nil {
+			return
+		}
+		if f != nil {
+			*v = *f
+		}
+	}
+
+	vf.BlackStart, err = mf.float("lavfi.black_start")
+	if err != nil {
+		return
+	}
+
+	vf.BlackEnd, err = mf.float("lavfi.black_end")
+	if err != nil {
+		return
+	}
+
+	vf.FreezeStart, err = mf.float("lavfi.freezedetect.freeze_start")
+	if err != nil {
+		return
+	}
+
+	vf.FreezeEnd, err = mf.float("lavfi.freezedetect.freeze_end")
+	if err != nil {
+		return
+	}
+
+	vf.FreezeDuration, err = mf.float("lavfi.freezedetect.freeze_duration")
+
+	return
+}
+
+func (mf metadataFrame) audioFrame() (af AudioFrame, err error) {
+	af.Time, err = parseFrameTime(mf.time)
+	if err != nil {
+		return
+	}
+
+	af.SilenceStart, err = mf.float("lavfi.silence_start")
+	if err != nil {
+		return
+	}
+
+	af.SilenceEnd, err = mf.float("lavfi.silence_end")
+	if err != nil {
+		return
+	}
+
+	af.SilenceDuration, err = mf.float("lavfi.silence_duration")
+	if err != nil {
+		return
+	}
+
+	for key, v := range map[string]*float64{
+		"lavfi.astats.Overall.RMS_level":  &af.RMSLevel,
+		"lavfi.astats.Overall.Peak_level": &af.PeakLevel,
+		"lavfi.astats.Overall.Peak_count": &af.PeakCount,
+		"lavfi.astats.Overall.DC_offset":  &af.DCOffset,
+	} {
+		var f *float64
+
+		f, err = mf.float(key)
+		if err != nil {
+			return
+		}
+		if f != nil {
+			*v = *f
+		}
+	}
+
+	af.MomentaryLoudness, err = mf.float("lavfi.r128.M")
+	if err != nil {
+		return
+	}
+
+	af.IntegratedLoudness, err = mf.float("lavfi.r128.I")
+	if err != nil {
+		return
+	}
+
+	// True peaks are reported per channel, frame true peak is the maximum.
+	for key := range mf.tags {
+		if !strings.HasPrefix(key, "lavfi.r128.true_peaks_ch") {
+			continue
+		}
+
+		var tp *float64
+
+		tp, err = mf.float(key)
+		if err != nil {
+			return
+		}
+		if af.TruePeak == nil || *tp > *af.TruePeak {
+			af.TruePeak = tp
+		}
+	}
+
+	return
+}
@@ -0,0 +1,252 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// benchmarkFrames is count of frames in benchmark metadata, which is 10
+// minutes of 25 fps video.
+const benchmarkFrames = 15000
+
+func videoMetadata(frames int) []byte {
+	var b bytes.Buffer
+	for i := 0; i < frames; i++ {
+		fmt.Fprintf(&b, "frame:%d    pts:%d    pts_time:%.2f\n", i, i*3600,
+			float64(i)/25)
+		fmt.Fprintf(&b, "lavfi.signalstats.TOUT=%f\n", float64(i%100)/1000)
+		b.WriteString("lavfi.signalstats.YAVG=110.5\n")
+		b.WriteString("lavfi.signalstats.YMIN=16\n")
+		b.WriteString("lavfi.signalstats.YMAX=235\n")
+		b.WriteString("lavfi.signalstats.UAVG=127.2\n")
+		b.WriteString("lavfi.signalstats.VAVG=128.9\n")
+		b.WriteString("lavfi.signalstats.SATAVG=20.1\n")
+		b.WriteString("lavfi.signalstats.HUEAVG=150.3\n")
+		b.WriteString("lavfi.blur=3.2\n")
+	}
+	return b.Bytes()
+}
+
+func audioMetadata(frames int) []byte {
+	var b bytes.Buffer
+	for i := 0; i < frames; i++ {
+		fmt.Fprintf(&b, "frame:%d    pts:%d    pts_time:%.3f\n", i, i*1024,
+			float64(i)*1024/48000)
+		b.WriteString("lavfi.r128.M=-23.1\n")
+		b.WriteString("lavfi.r128.I=-23.4\n")
+		b.WriteString("lavfi.r128.true_peaks_ch0=-3.2\n")
+		b.WriteString("lavfi.astats.Overall.RMS_level=-25.3\n")
+		b.WriteString("lavfi.astats.Overall.Peak_level=-3.5\n")
+		b.WriteString("lavfi.astats.Overall.Peak_count=2\n")
+		b.WriteString("lavfi.astats.Overall.DC_offset=0.0001\n")
+	}
+	return b.Bytes()
+}
+
+func BenchmarkScanVideoMetadata(b *testing.B) {
+	data := videoMetadata(benchmarkFrames)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var frames int
+		err := scanMetadata(bytes.NewReader(data), func(mf metadataFrame) error {
+			_, err := mf.videoFrame()
+			frames++
+			return err
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if frames != benchmarkFrames {
+			b.Fatalf("got %d frames, expected %d", frames, benchmarkFrames)
+		}
+	}
+}
+
+func BenchmarkScanAudioMetadata(b *testing.B) {
+	data := audioMetadata(benchmarkFrames)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var frames int
+		err := scanMetadata(bytes.NewReader(data), func(mf metadataFrame) error {
+			_, err := mf.audioFrame()
+			frames++
+			return err
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if frames != benchmarkFrames {
+			b.Fatalf("got %d frames, expected %d", frames, benchmarkFrames)
+		}
+	}
+}
+
+// bufferedVideoResult is ffprobe JSON output decoded in one go, like video
+// frames were probed before metadata streaming. It is baseline for
+// metadata scan benchmarks.
+type bufferedVideoResult struct {
+	Frames []struct {
+		Time string `json:"best_effort_timestamp_time"`
+		Tags struct {
+			Tout           string  `json:"lavfi.signalstats.TOUT"`
+			BlackStart     *string `json:"lavfi.black_start"`
+			BlackEnd       *string `json:"lavfi.black_end"`
+			FreezeStart    *string `json:"lavfi.freezedetect.freeze_start"`
+			FreezeEnd      *string `json:"lavfi.freezedetect.freeze_end"`
+			FreezeDuration *string `json:"lavfi.freezedetect.freeze_duration"`
+		} `json:"tags"`
+	} `json:"frames"`
+}
+
+// bufferedAudioResult is audio counterpart of bufferedVideoResult.
+type bufferedAudioResult struct {
+	Frames []struct {
+		Time string `json:"best_effort_timestamp_time"`
+		Tags struct {
+			SilenceStart    *string `json:"lavfi.silence_start"`
+			SilenceEnd      *string `json:"lavfi.silence_end"`
+			SilenceDuration *string `json:"lavfi.silence_duration"`
+		} `json:"tags"`
+	} `json:"frames"`
+}
+
+func videoJSON(frames int) []byte {
+	var b bytes.Buffer
+	b.WriteString(`{"frames":[`)
+	for i := 0; i < frames; i++ {
+		if i != 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, `{"media_type":"video","stream_index":0,`+
+			`"best_effort_timestamp":%d,"best_effort_timestamp_time":"%f",`+
+			`"width":1920,"height":1080,"pix_fmt":"yuv420p",`+
+			`"tags":{"lavfi.signalstats.TOUT":"%f",`+
+			`"lavfi.signalstats.YAVG":"110.5","lavfi.signalstats.YMIN":"16",`+
+			`"lavfi.signalstats.YMAX":"235","lavfi.signalstats.UAVG":"127.2",`+
+			`"lavfi.signalstats.VAVG":"128.9",`+
+			`"lavfi.signalstats.SATAVG":"20.1",`+
+			`"lavfi.signalstats.HUEAVG":"150.3","lavfi.blur":"3.2"}}`,
+			i*3600, float64(i)/25, float64(i%100)/1000)
+	}
+	b.WriteString("]}")
+	return b.Bytes()
+}
+
+func audioJSON(frames int) []byte {
+	var b bytes.Buffer
+	b.WriteString(`{"frames":[`)
+	for i := 0; i < frames; i++ {
+		if i != 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, `{"media_type":"audio","stream_index":1,`+
+			`"best_effort_timestamp":%d,"best_effort_timestamp_time":"%f",`+
+			`"nb_samples":1024,"channels":2,"tags":{"lavfi.r128.M":"-23.1",`+
+			`"lavfi.r128.I":"-23.4","lavfi.r128.true_peaks_ch0":"-3.2",`+
+			`"lavfi.astats.Overall.RMS_level":"-25.3",`+
+			`"lavfi.astats.Overall.Peak_level":"-3.5",`+
+			`"lavfi.astats.Overall.Peak_count":"2",`+
+			`"lavfi.astats.Overall.DC_offset":"0.0001"}}`,
+			i*1024, float64(i)*1024/48000)
+	}
+	b.WriteString("]}")
+	return b.Bytes()
+}
+
+func optionalFloat(s *string) (*float64, error) {
+	if s == nil {
+		return nil, nil
+	}
+	f, err := parseFrameTime(*s)
+	return &f, err
+}
+
+func BenchmarkDecodeBufferedVideoJSON(b *testing.B) {
+	data := videoJSON(benchmarkFrames)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var out bytes.Buffer
+		out.ReadFrom(bytes.NewReader(data))
+
+		var res bufferedVideoResult
+		err := json.NewDecoder(&out).Decode(&res)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		vfs := make([]VideoFrame, 0, len(res.Frames))
+		for _, f := range res.Frames {
+			var vf VideoFrame
+			vf.Time, err = parseFrameTime(f.Time)
+			if err != nil {
+				b.Fatal(err)
+			}
+			vf.Tout, err = parseFrameTime(f.Tags.Tout)
+			if err != nil {
+				b.Fatal(err)
+			}
+			vf.BlackStart, err = optionalFloat(f.Tags.BlackStart)
+			if err != nil {
+				b.Fatal(err)
+			}
+			vf.FreezeStart, err = optionalFloat(f.Tags.FreezeStart)
+			if err != nil {
+				b.Fatal(err)
+			}
+			vfs = append(vfs, vf)
+		}
+		if len(vfs) != benchmarkFrames {
+			b.Fatalf("got %d frames, expected %d", len(vfs), benchmarkFrames)
+		}
+	}
+}
+
+func BenchmarkDecodeBufferedAudioJSON(b *testing.B) {
+	data := audioJSON(benchmarkFrames)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var out bytes.Buffer
+		out.ReadFrom(bytes.NewReader(data))
+
+		var res bufferedAudioResult
+		err := json.NewDecoder(&out).Decode(&res)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		afs := make([]AudioFrame, 0, len(res.Frames))
+		for _, f := range res.Frames {
+			var af AudioFrame
+			af.Time, err = parseFrameTime(f.Time)
+			if err != nil {
+				b.Fatal(err)
+			}
+			af.SilenceStart, err = optionalFloat(f.Tags.SilenceStart)
+			if err != nil {
+				b.Fatal(err)
+			}
+			afs = append(afs, af)
+		}
+		if len(afs) != benchmarkFrames {
+			b.Fatalf("got %d frames, expected %d", len(afs), benchmarkFrames)
+		}
+	}
+}
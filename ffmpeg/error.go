@@ -0,0 +1,125 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dimuls/camtester/entity"
+)
+
+// Reasons of ffmpeg and ffprobe failures.
+const (
+	ReasonUnauthorized      = "unauthorized"
+	ReasonForbidden         = "forbidden"
+	ReasonNotFound          = "not_found"
+	ReasonServerError       = "server_error"
+	ReasonConnectionRefused = "connection_refused"
+	ReasonHostNotFound      = "host_not_found"
+	ReasonHostUnreachable   = "host_unreachable"
+	ReasonTimeout           = "timeout"
+	ReasonUnsupportedCodec  = "unsupported_codec"
+	ReasonInvalidData       = "invalid_data"
+	ReasonUnknown           = "unknown"
+)
+
+// reasonPatterns maps ffmpeg and ffprobe error output substrings to
+// failure reasons. Patterns are matched case insensitive in order, so more
+// specific ones go first.
+var reasonPatterns = []struct {
+	pattern string
+	reason  string
+}{
+	{"401 unauthorized", ReasonUnauthorized},
+	{"403 forbidden", ReasonForbidden},
+	{"404 not found", ReasonNotFound},
+	{"454 session not found", ReasonNotFound},
+	{"no such file or directory", ReasonNotFound},
+	{"5xx server error", ReasonServerError},
+	{"500 internal server error", ReasonServerError},
+	{"503 service unavailable", ReasonServerError},
+	{"connection refused", ReasonConnectionRefused},
+	{"failed to resolve hostname", ReasonHostNotFound},
+	{"name or service not known", ReasonHostNotFound},
+	{"no route to host", ReasonHostUnreachable},
+	{"network is unreachable", ReasonHostUnreachable},
+	{"connection timed out", ReasonTimeout},
+	{"operation timed out", ReasonTimeout},
+	{"immediate exit requested", ReasonTimeout},
+	{"decoder (codec", ReasonUnsupportedCodec},
+	{"unsupported codec", ReasonUnsupportedCodec},
+	{"could not find codec parameters", ReasonUnsupportedCodec},
+	{"invalid data found when processing input", ReasonInvalidData},
+	{"error while decoding", ReasonInvalidData},
+}
+
+// Error is failed ffmpeg or ffprobe run with reason parsed from its error
+// output.
+type Error struct {
+	Op     string
+	Reason string
+	Output string
+	Err    error
+}
+
+func newError(op string, err error, output string) *Error {
+	return &Error{
+		Op:     op,
+		Reason: parseReason(output),
+		Output: output,
+		Err:    err,
+	}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %v, reason: %s, error text: %s", e.Op, e.Err,
+		e.Reason, e.Output)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func parseReason(output string) string {
+	output = strings.ToLower(output)
+
+	for _, p := range reasonPatterns {
+		if strings.Contains(output, p.pattern) {
+			return p.reason
+		}
+	}
+
+	return ReasonUnknown
+}
+
+var reasonErrorCodes = map[string]string{
+	ReasonUnauthorized:      entity.ErrorCodeAuthFailed,
+	ReasonForbidden:         entity.ErrorCodeAuthFailed,
+	ReasonNotFound:          entity.ErrorCodeStreamNotFound,
+	ReasonServerError:       entity.ErrorCodeConnectionFailed,
+	ReasonConnectionRefused: entity.ErrorCodeConnectionFailed,
+	ReasonHostNotFound:      entity.ErrorCodeConnectionFailed,
+	ReasonHostUnreachable:   entity.ErrorCodeConnectionFailed,
+	ReasonTimeout:           entity.ErrorCodeTimeout,
+	ReasonUnsupportedCodec:  entity.ErrorCodeUnsupportedCodec,
+	ReasonInvalidData:       entity.ErrorCodeDecodeFailed,
+}
+
+// ErrorCode classifies error returned by functions of this package into
+// task error code.
+func ErrorCode(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return entity.ErrorCodeTimeout
+	}
+
+	var fe *Error
+
+	if errors.As(err, &fe) {
+		if code, ok := reasonErrorCodes[fe.Reason]; ok {
+			return code
+		}
+	}
+
+	return entity.ErrorCodeInternal
+}
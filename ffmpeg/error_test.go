@@ -0,0 +1,60 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/dimuls/camtester/entity"
+)
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		file string
+		code string
+	}{
+		{"unauthorized.txt", entity.ErrorCodeAuthFailed},
+		{"not_found.txt", entity.ErrorCodeStreamNotFound},
+		{"connection_refused.txt", entity.ErrorCodeConnectionFailed},
+		{"timeout.txt", entity.ErrorCodeTimeout},
+		{"rw_timeout.txt", entity.ErrorCodeTimeout},
+		{"unsupported_codec.txt", entity.ErrorCodeUnsupportedCodec},
+		{"invalid_data.txt", entity.ErrorCodeDecodeFailed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			output, err := ioutil.ReadFile(filepath.Join("testdata", test.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = fmt.Errorf("analyze stream: %w", newError("run ffmpeg",
+				errors.New("exit status 1"), string(output)))
+
+			if code := ErrorCode(err); code != test.code {
+				t.Errorf("got code %s, expected %s", code, test.code)
+			}
+		})
+	}
+}
+
+func TestErrorCodeDeadlineExceeded(t *testing.T) {
+	err := fmt.Errorf("run ffmpeg: %w", context.DeadlineExceeded)
+
+	if code := ErrorCode(err); code != entity.ErrorCodeTimeout {
+		t.Errorf("got code %s, expected %s", code, entity.ErrorCodeTimeout)
+	}
+}
+
+func TestErrorCodeUnknown(t *testing.T) {
+	err := newError("run ffmpeg", errors.New("exit status 1"),
+		"something unexpected happened\n")
+
+	if code := ErrorCode(err); code != entity.ErrorCodeInternal {
+		t.Errorf("got code %s, expected %s", code, entity.ErrorCodeInternal)
+	}
+}
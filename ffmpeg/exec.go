@@ -0,0 +1,64 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ioTimeout is timeout of stream input read and write operations. Stalled
+// stream fails with timeout instead of hanging until context is done.
+const ioTimeout = 10 * time.Second
+
+// killWaitDelay is how long command wait waits for output pipes to close
+// after process group is killed.
+const killWaitDelay = 5 * time.Second
+
+// command returns command which runs in its own process group. Whole group
+// is killed when context is done, so no child process outlives task.
+func command(ctx context.Context, path string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, path, args...)
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = killWaitDelay
+
+	return cmd
+}
+
+// runError returns error of failed ffmpeg or ffprobe run. If process is
+// killed because context is done, context error is wrapped instead.
+func runError(ctx context.Context, op string, err error,
+	output string) error {
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("%s: %w", op, ctxErr)
+	}
+
+	return newError(op, err, output)
+}
+
+// inputArgs returns ffmpeg stream input arguments. Transport is RTSP
+// transport, empty transport means ffmpeg default. RTSP and RTSPS socket
+// timeout option is timeout since ffmpeg 5, where stimeout is removed.
+func inputArgs(uri, transport string) []string {
+	timeout := strconv.FormatInt(ioTimeout.Microseconds(), 10)
+
+	args := []string{"-rw_timeout", timeout}
+
+	if strings.HasPrefix(uri, "rtsp://") || strings.HasPrefix(uri, "rtsps://") {
+		args = append(args, "-timeout", timeout)
+	}
+
+	if transport != "" {
+		args = append(args, "-rtsp_transport", transport)
+	}
+
+	return append(args, "-i", uri)
+}
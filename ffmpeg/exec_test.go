@@ -0,0 +1,31 @@
+package ffmpeg
+
+import (
+	"testing"
+)
+
+func TestInputArgsTimeout(t *testing.T) {
+	tests := []struct {
+		uri     string
+		timeout bool
+	}{
+		{"rtsp://camera/stream", true},
+		{"rtsps://camera/stream", true},
+		{"http://camera/stream.mjpg", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.uri, func(t *testing.T) {
+			var timeout bool
+			for _, arg := range inputArgs(test.uri, "") {
+				if arg == "-timeout" {
+					timeout = true
+				}
+			}
+			if timeout != test.timeout {
+				t.Errorf("expected timeout option %t, got %t", test.timeout,
+					timeout)
+			}
+		})
+	}
+}
@@ -3,32 +3,11 @@ package ffmpeg
 import (
 	"bufio"
 	"bytes"
-	"fmt"
-	"os/exec"
+	"context"
 	"strconv"
 	"strings"
 )
 
-func RecordStream(ffmpegPath, uri string, durationSec int, destFile string) (int, error) {
-	cmd := exec.Command(ffmpegPath, "-v", "error", "-y", "-i", uri,
-		"-t", strconv.Itoa(durationSec), "-c:a", "copy", "-c:v", "copy",
-		destFile)
-
-	var errText bytes.Buffer
-
-	cmd.Stderr = &errText
-
-	errLines := strings.Count(errText.String(), "\n")
-
-	err := cmd.Run()
-	if err != nil {
-		return errLines, fmt.Errorf("run ffmpeg: %w, error text: %s", err,
-			errText.String())
-	}
-
-	return errLines, nil
-}
-
 type Check struct {
 	DurationSec      int `json:"duration_sec"`
 	RTPMissedPackets int `json:"rtp_missed_packets"`
@@ -37,11 +16,16 @@ type Check struct {
 	MaxDelayReaches  int `json:"max_delay_reaches"`
 }
 
-func CheckStream(ffmpegPath, uri string, durationSec int) (c Check, err error) {
+func CheckStream(ctx context.Context, ffmpegPath, uri, transport string,
+	durationSec int) (c Check, err error) {
+
 	c.DurationSec = durationSec
 
-	cmd := exec.Command(ffmpegPath, "-v", "warning", "-i", uri,
-		"-t", strconv.Itoa(durationSec), "-f", "null", "/dev/nulll")
+	args := append([]string{"-v", "warning"}, inputArgs(uri, transport)...)
+	args = append(args, "-t", strconv.Itoa(durationSec), "-f", "null",
+		"/dev/nulll")
+
+	cmd := command(ctx, ffmpegPath, args...)
 
 	buf := bytes.NewBuffer(nil)
 
@@ -50,8 +34,7 @@ func CheckStream(ffmpegPath, uri string, durationSec int) (c Check, err error) {
 
 	err = cmd.Run()
 	if err != nil {
-		err = fmt.Errorf("run ffmpeg: %w, output: %s", err,
-			buf.String())
+		err = runError(ctx, "run ffmpeg", err, buf.String())
 		return
 	}
 
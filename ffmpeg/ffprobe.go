@@ -1,292 +1,203 @@
 package ffmpeg
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"os/exec"
+	"io"
+	"io/ioutil"
 	"strconv"
+	"strings"
 )
 
+// VideoFrame is probed video frame. Time is frame timestamp in seconds,
+// markers are timestamps of detected segments start and end. Signal
+// statistics are luma (Y), chroma (U, V), saturation and hue averages and
+// luma range, blur is blurdetect filter blur measure.
 type VideoFrame struct {
-	Tout           float64  `json:"lavfi.signalstats.TOUT"`
-	BlackStart     *float64 `json:"lavfi.black_start"`
-	BlackEnd       *float64 `json:"lavfi.black_end"`
-	FreezeStart    *float64 `json:"lavfi.freezedetect.freeze_start"`
-	FreezeEnd      *float64 `json:"lavfi.freezedetect.freeze_end"`
-	FreezeDuration *float64 `json:"lavfi.freezedetect.freeze_duration"`
+	Time           float64
+	Tout           float64
+	YAvg           float64
+	YMin           float64
+	YMax           float64
+	UAvg           float64
+	VAvg           float64
+	SatAvg         float64
+	HueAvg         float64
+	Blur           float64
+	BlackStart     *float64
+	BlackEnd       *float64
+	FreezeStart    *float64
+	FreezeEnd      *float64
+	FreezeDuration *float64
 }
 
-type videoStream struct {
-	Index int `json:"index"`
+// VideoOptions are video probe filters thresholds. Zero fields mean
+// ffmpeg defaults.
+type VideoOptions struct {
+	BlackMinDurationSec  float64
+	BlackPixelThreshold  float64
+	FreezeNoiseDB        float64
+	FreezeMinDurationSec float64
 }
 
-type videoCheck struct {
-	Streams []audioStream `json:"streams"`
-}
-
-type videoFrame struct {
-	Tags *struct {
-		Tout           string  `json:"lavfi.signalstats.TOUT"`
-		BlackStart     *string `json:"lavfi.black_start"`
-		BlackEnd       *string `json:"lavfi.black_end"`
-		FreezeStart    *string `json:"lavfi.freezedetect.freeze_start"`
-		FreezeEnd      *string `json:"lavfi.freezedetect.freeze_end"`
-		FreezeDuration *string `json:"lavfi.freezedetect.freeze_duration"`
-	} `json:"tags"`
-}
-
-type videoProbeResult struct {
-	Frames []videoFrame `json:"frames"`
-}
-
-func ProbeVideo(ffprobePath, fileName string) ([]VideoFrame, error) {
-
-	var (
-		out     bytes.Buffer
-		errText bytes.Buffer
-	)
-
-	cmd := exec.Command(ffprobePath,
-		"-v", "error",
-		"-i", fileName,
-		"-show_streams",
-		"-select_streams", "v",
-		"-print_format",
-		"json",
-	)
-
-	cmd.Stdout = &out
-	cmd.Stderr = &errText
-
-	err := cmd.Run()
-	if err != nil {
-		return nil, fmt.Errorf(
-			"run ffprobe video stream check: %w, error text: %s",
-			err, errText.String())
+func (o VideoOptions) filters() string {
+	black := "blackdetect"
+	var blackOpts []string
+	if o.BlackMinDurationSec != 0 {
+		blackOpts = append(blackOpts, "d="+formatFloat(o.BlackMinDurationSec))
 	}
-
-	var check audioCheck
-
-	err = json.NewDecoder(&out).Decode(&check)
-	if err != nil {
-		return nil, fmt.Errorf("JSON decode check: %w", err)
+	if o.BlackPixelThreshold != 0 {
+		blackOpts = append(blackOpts, "pix_th="+formatFloat(o.BlackPixelThreshold))
 	}
-
-	if len(check.Streams) == 0 {
-		return nil, nil
+	if len(blackOpts) != 0 {
+		black += "=" + strings.Join(blackOpts, ":")
 	}
 
-	cmd = exec.Command(ffprobePath,
-		"-v", "error",
-		"-f", "lavfi",
-		"movie="+fileName+",signalstats=stat=tout,blackdetect,freezedetect",
-		"-show_frames",
-		"-print_format",
-		"json",
-	)
-
-	out.Reset()
-	errText.Reset()
-
-	cmd.Stdout = &out
-	cmd.Stderr = &errText
-
-	err = cmd.Run()
-	if err != nil {
-		return nil, fmt.Errorf("run ffprobe: %w, error text: %s",
-			err, errText.String())
+	freeze := "freezedetect"
+	var freezeOpts []string
+	if o.FreezeNoiseDB != 0 {
+		freezeOpts = append(freezeOpts, "n="+formatFloat(o.FreezeNoiseDB)+"dB")
 	}
-
-	var res videoProbeResult
-
-	err = json.NewDecoder(&out).Decode(&res)
-	if err != nil {
-		return nil, fmt.Errorf("JSON decode result: %w", err)
+	if o.FreezeMinDurationSec != 0 {
+		freezeOpts = append(freezeOpts, "d="+formatFloat(o.FreezeMinDurationSec))
 	}
-
-	var vfs []VideoFrame
-
-	for _, f := range res.Frames {
-		var vf VideoFrame
-
-		if f.Tags != nil {
-
-			vf.Tout, err = strconv.ParseFloat(f.Tags.Tout, 64)
-			if err != nil {
-				return nil, fmt.Errorf("parse Tout: %w", err)
-			}
-
-			if vf.BlackStart != nil {
-				bs, err := strconv.ParseFloat(*f.Tags.BlackStart, 64)
-				if err != nil {
-					return nil, fmt.Errorf("parse BlackStart: %w", err)
-				}
-				vf.BlackStart = &bs
-			}
-
-			if vf.BlackEnd != nil {
-				be, err := strconv.ParseFloat(*f.Tags.BlackEnd, 64)
-				if err != nil {
-					return nil, fmt.Errorf("parse BlackEnd: %w", err)
-				}
-				vf.BlackEnd = &be
-			}
-
-			if vf.FreezeStart != nil {
-				fs, err := strconv.ParseFloat(*f.Tags.FreezeStart, 64)
-				if err != nil {
-					return nil, fmt.Errorf("parse FreezeStart: %w", err)
-				}
-				vf.FreezeStart = &fs
-			}
-
-			if vf.FreezeEnd != nil {
-				fe, err := strconv.ParseFloat(*f.Tags.FreezeEnd, 64)
-				if err != nil {
-					return nil, fmt.Errorf("parse FreezeEnd: %w", err)
-				}
-				vf.FreezeEnd = &fe
-			}
-
-			if vf.FreezeDuration != nil {
-				fd, err := strconv.ParseFloat(*f.Tags.FreezeDuration, 64)
-				if err != nil {
-					return nil, fmt.Errorf("parse FreezeDuration: %w", err)
-				}
-				vf.FreezeDuration = &fd
-			}
-		}
-
-		vfs = append(vfs, vf)
+	if len(freezeOpts) != 0 {
+		freeze += "=" + strings.Join(freezeOpts, ":")
 	}
 
-	return vfs, nil
-}
-
-type AudioFrame struct {
-	SilenceStart    *float64 `json:"lavfi.silence_start"`
-	SilenceEnd      *float64 `json:"lavfi.silence_end"`
-	SilenceDuration *float64 `json:"lavfi.silence_duration"`
+	return "signalstats=stat=tout,blurdetect," + black + "," + freeze
 }
 
-type audioStream struct {
-	Index int `json:"index"`
+// parseFrameTime parses frame timestamp. Frames without timestamp have
+// N/A, NOPTS or empty time, which is parsed as zero.
+func parseFrameTime(t string) (float64, error) {
+	if t == "" || t == "N/A" || t == "NOPTS" {
+		return 0, nil
+	}
+	ft, err := strconv.ParseFloat(t, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse frame time: %w", err)
+	}
+	return ft, nil
 }
 
-type audioCheck struct {
-	Streams []audioStream `json:"streams"`
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
 }
 
-type audioFrame struct {
-	Tags *struct {
-		SilenceStart    *string `json:"lavfi.silence_start"`
-		SilenceEnd      *string `json:"lavfi.silence_end"`
-		SilenceDuration *string `json:"lavfi.silence_duration"`
-	} `json:"tags"`
+// AudioFrame is probed audio frame. Time is frame timestamp in seconds,
+// markers are timestamps of detected silence start and end. Levels are
+// astats frame levels in dBFS, peak count is count of samples with peak
+// value. Loudness values are EBU R128 momentary and integrated since sample
+// start loudness in LUFS and true peak in dBTP, they are nil until ebur128
+// filter outputs them.
+type AudioFrame struct {
+	Time            float64
+	SilenceStart    *float64
+	SilenceEnd      *float64
+	SilenceDuration *float64
+
+	RMSLevel  float64
+	PeakLevel float64
+	PeakCount float64
+	DCOffset  float64
+
+	MomentaryLoudness  *float64
+	IntegratedLoudness *float64
+	TruePeak           *float64
 }
 
-type audioProbeResult struct {
-	Frames []audioFrame `json:"frames"`
+// AudioOptions are audio probe filters thresholds. Zero fields mean ffmpeg
+// defaults.
+type AudioOptions struct {
+	SilenceNoiseDB        float64
+	SilenceMinDurationSec float64
 }
 
-func ProbeAudio(ffprobePath, fileName string) ([]AudioFrame, error) {
-	var (
-		out     bytes.Buffer
-		errText bytes.Buffer
-	)
-
-	cmd := exec.Command(ffprobePath,
-		"-v", "error",
-		"-i", fileName,
-		"-show_streams",
-		"-select_streams", "a",
-		"-print_format",
-		"json",
-	)
-
-	cmd.Stdout = &out
-	cmd.Stderr = &errText
-
-	err := cmd.Run()
-	if err != nil {
-		return nil, fmt.Errorf(
-			"run ffprobe audio stream check: %w, error text: %s",
-			err, errText.String())
+func (o AudioOptions) filters() string {
+	silence := "silencedetect"
+	var silenceOpts []string
+	if o.SilenceNoiseDB != 0 {
+		silenceOpts = append(silenceOpts, "n="+formatFloat(o.SilenceNoiseDB)+"dB")
 	}
-
-	var check audioCheck
-
-	err = json.NewDecoder(&out).Decode(&check)
-	if err != nil {
-		return nil, fmt.Errorf("JSON decode check: %w", err)
+	if o.SilenceMinDurationSec != 0 {
+		silenceOpts = append(silenceOpts, "d="+formatFloat(o.SilenceMinDurationSec))
 	}
-
-	if len(check.Streams) == 0 {
-		return nil, nil
+	if len(silenceOpts) != 0 {
+		silence += "=" + strings.Join(silenceOpts, ":")
 	}
+	return silence + "," + loudnessFilters
+}
 
-	cmd = exec.Command(ffprobePath,
-		"-v", "error",
-		"-f", "lavfi",
-		"amovie="+fileName+",silencedetect",
-		"-show_frames",
-		"-print_format",
-		"json",
-	)
-
-	out.Reset()
-	errText.Reset()
+// loudnessFilters add loudness and levels metadata to every audio frame.
+const loudnessFilters = "ebur128=metadata=1:peak=true," +
+	"astats=metadata=1:reset=1:measure_perchannel=none:" +
+	"measure_overall=RMS_level+Peak_level+Peak_count+DC_offset"
 
-	cmd.Stdout = &out
-	cmd.Stderr = &errText
+// decodeArrays decodes ffprobe JSON output token by token and calls decode
+// function of array with decoder positioned at every element of array.
+// Sections without decode function are skipped.
+func decodeArrays(r io.Reader,
+	decode map[string]func(d *json.Decoder) error) error {
 
-	err = cmd.Run()
-	if err != nil {
-		return nil, fmt.Errorf("run ffprobe: %w, error text: %s",
-			err, errText.String())
-	}
+	d := json.NewDecoder(r)
 
-	var res audioProbeResult
-
-	err = json.NewDecoder(&out).Decode(&res)
+	err := expectDelim(d, '{')
 	if err != nil {
-		return nil, fmt.Errorf("JSON decode result: %w", err)
+		return err
 	}
 
-	var afs []AudioFrame
+	for d.More() {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
 
-	for _, f := range res.Frames {
-		var af AudioFrame
+		key, _ := t.(string)
 
-		if f.Tags != nil {
-			if f.Tags.SilenceStart != nil {
-				ss, err := strconv.ParseFloat(*f.Tags.SilenceStart, 64)
-				if err != nil {
-					return nil, fmt.Errorf("parse SilenceStart: %w", err)
-				}
-				af.SilenceStart = &ss
+		decodeElem, ok := decode[key]
+		if !ok {
+			var skipped json.RawMessage
+			err = d.Decode(&skipped)
+			if err != nil {
+				return err
 			}
+			continue
+		}
 
-			if f.Tags.SilenceEnd != nil {
-				se, err := strconv.ParseFloat(*f.Tags.SilenceEnd, 64)
-				if err != nil {
-					return nil, fmt.Errorf("parse SilenceEnd: %w", err)
-				}
-				af.SilenceEnd = &se
-			}
+		err = expectDelim(d, '[')
+		if err != nil {
+			return err
+		}
 
-			if f.Tags.SilenceDuration != nil {
-				sd, err := strconv.ParseFloat(*f.Tags.SilenceDuration, 64)
-				if err != nil {
-					return nil, fmt.Errorf("parse SilenceDuration: %w", err)
-				}
-				af.SilenceDuration = &sd
+		for d.More() {
+			err = decodeElem(d)
+			if err != nil {
+				return err
 			}
 		}
 
-		afs = append(afs, af)
+		err = expectDelim(d, ']')
+		if err != nil {
+			return err
+		}
 	}
 
-	return afs, nil
+	return expectDelim(d, '}')
+}
+
+// drain reads rest of output, so process is not blocked on write.
+func drain(r io.Reader) {
+	io.Copy(ioutil.Discard, r)
+}
+
+func expectDelim(d *json.Decoder, delim json.Delim) error {
+	t, err := d.Token()
+	if err != nil {
+		return err
+	}
+	if t != delim {
+		return fmt.Errorf("unexpected token %v, expected %v", t, delim)
+	}
+	return nil
 }
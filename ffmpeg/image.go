@@ -0,0 +1,156 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"strconv"
+)
+
+// maxPSNR is PSNR of identical images, which ffmpeg reports as inf.
+const maxPSNR = 100
+
+// GrabFrame returns first video frame of stream as JPEG image. Frame is
+// scaled to given width keeping aspect ratio, zero width keeps frame size.
+func GrabFrame(ctx context.Context, ffmpegPath, uri, transport string,
+	width int) ([]byte, error) {
+
+	args := append([]string{"-v", "error", "-nostdin"},
+		inputArgs(uri, transport)...)
+	args = append(args, "-map", "0:v:0", "-frames:v", "1")
+
+	if width > 0 {
+		args = append(args, "-vf", "scale="+strconv.Itoa(width)+":-2")
+	}
+
+	args = append(args, "-q:v", "3", "-c:v", "mjpeg", "-f", "image2pipe",
+		"pipe:1")
+
+	cmd := command(ctx, ffmpegPath, args...)
+
+	var image, errText bytes.Buffer
+
+	cmd.Stdout = &image
+	cmd.Stderr = &errText
+
+	err := cmd.Run()
+	if err != nil {
+		return nil, runError(ctx, "run ffmpeg", err, errText.String())
+	}
+
+	if image.Len() == 0 {
+		return nil, newError("run ffmpeg", errors.New("no frame grabbed"),
+			errText.String())
+	}
+
+	return image.Bytes(), nil
+}
+
+// Similarity is similarity of image and reference image.
+type Similarity struct {
+	SSIM float64 `json:"ssim"`
+	PSNR float64 `json:"psnr"`
+}
+
+// CompareImages computes SSIM and PSNR of image against reference image.
+// Image is scaled to reference image size before comparison.
+func CompareImages(ctx context.Context, ffmpegPath string, image,
+	reference []byte) (s Similarity, err error) {
+
+	imageFile, err := writeTempFile("camtester-image-", image)
+	if err != nil {
+		err = fmt.Errorf("write image: %w", err)
+		return
+	}
+	defer os.Remove(imageFile)
+
+	referenceFile, err := writeTempFile("camtester-reference-", reference)
+	if err != nil {
+		err = fmt.Errorf("write reference: %w", err)
+		return
+	}
+	defer os.Remove(referenceFile)
+
+	// ssim passes main frame through with its metadata, so psnr is chained
+	// after it and both values are printed with single metadata filter.
+	filter := "[0:v]format=yuv420p[img];[1:v]format=yuv420p[ref];" +
+		"[img][ref]scale2ref[simg][sref];[sref]split[ref1][ref2];" +
+		"[simg][ref1]ssim[ssim];[ssim][ref2]psnr," +
+		"metadata=mode=print:file=pipe\\:1"
+
+	cmd := command(ctx, ffmpegPath, "-v", "error", "-nostdin",
+		"-i", imageFile, "-i", referenceFile,
+		"-filter_complex", filter, "-f", "null", "-")
+
+	var out, errText bytes.Buffer
+
+	cmd.Stdout = &out
+	cmd.Stderr = &errText
+
+	err = cmd.Run()
+	if err != nil {
+		err = runError(ctx, "run ffmpeg", err, errText.String())
+		return
+	}
+
+	var found bool
+
+	err = scanMetadata(&out, func(mf metadataFrame) error {
+		ssim, err := mf.float("lavfi.ssim.All")
+		if err != nil {
+			return err
+		}
+
+		psnr, err := mf.float("lavfi.psnr.psnr_avg")
+		if err != nil {
+			return err
+		}
+
+		if ssim == nil || psnr == nil {
+			return nil
+		}
+
+		s.SSIM = *ssim
+		s.PSNR = math.Min(*psnr, maxPSNR)
+		found = true
+
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("parse metadata: %w", err)
+		return
+	}
+
+	if !found {
+		err = newError("run ffmpeg", errors.New("no similarity metadata"),
+			errText.String())
+	}
+
+	return
+}
+
+func writeTempFile(prefix string, data []byte) (string, error) {
+	f, err := ioutil.TempFile("", prefix)
+	if err != nil {
+		return "", fmt.Errorf("create: %w", err)
+	}
+
+	_, err = f.Write(data)
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", fmt.Errorf("write: %w", err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("close: %w", err)
+	}
+
+	return f.Name(), nil
+}
@@ -0,0 +1,198 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Stream is ffprobe stream info.
+type Stream struct {
+	Index        int    `json:"index"`
+	CodecType    string `json:"codec_type"`
+	CodecName    string `json:"codec_name"`
+	Profile      string `json:"profile"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	RFrameRate   string `json:"r_frame_rate"`
+	AvgFrameRate string `json:"avg_frame_rate"`
+	BitRate      string `json:"bit_rate"`
+}
+
+// Packet is ffprobe packet info. Timestamps are in seconds, PTS and DTS are
+// nil if packet has no timestamp.
+type Packet struct {
+	StreamIndex int
+	PTSTime     *float64
+	DTSTime     *float64
+	Duration    float64
+	Size        int
+	Keyframe    bool
+}
+
+type probePacket struct {
+	StreamIndex  int    `json:"stream_index"`
+	PTSTime      string `json:"pts_time"`
+	DTSTime      string `json:"dts_time"`
+	DurationTime string `json:"duration_time"`
+	Size         string `json:"size"`
+	Flags        string `json:"flags"`
+}
+
+func parseOptionalTime(t string) (*float64, error) {
+	if t == "" || t == "N/A" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(t, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (pp probePacket) packet() (p Packet, err error) {
+	p.StreamIndex = pp.StreamIndex
+
+	p.PTSTime, err = parseOptionalTime(pp.PTSTime)
+	if err != nil {
+		return p, fmt.Errorf("parse pts_time: %w", err)
+	}
+
+	p.DTSTime, err = parseOptionalTime(pp.DTSTime)
+	if err != nil {
+		return p, fmt.Errorf("parse dts_time: %w", err)
+	}
+
+	d, err := parseOptionalTime(pp.DurationTime)
+	if err != nil {
+		return p, fmt.Errorf("parse duration_time: %w", err)
+	}
+	if d != nil {
+		p.Duration = *d
+	}
+
+	if pp.Size != "" {
+		p.Size, err = strconv.Atoi(pp.Size)
+		if err != nil {
+			return p, fmt.Errorf("parse size: %w", err)
+		}
+	}
+
+	p.Keyframe = strings.Contains(pp.Flags, "K")
+
+	return p, nil
+}
+
+// ParseRate parses ffprobe rational rate like 25/1. Zero is returned for
+// unknown rate 0/0.
+func ParseRate(r string) (float64, error) {
+	parts := strings.SplitN(r, "/", 2)
+
+	num, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse numerator: %w", err)
+	}
+
+	if len(parts) == 1 {
+		return num, nil
+	}
+
+	den, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse denominator: %w", err)
+	}
+
+	if den == 0 {
+		return 0, nil
+	}
+
+	return num / den, nil
+}
+
+// ProbePackets reads stream for given duration and calls handle for every
+// packet of selected streams while ffprobe is running. Empty streams
+// selector selects all streams. Streams info is returned after all packets
+// are handled.
+func ProbePackets(ctx context.Context, ffprobePath, uri, transport string,
+	durationSec int, selectStreams string, handle func(Packet) error) (
+	streams []Stream, err error) {
+
+	args := []string{"-v", "error"}
+
+	if selectStreams != "" {
+		args = append(args, "-select_streams", selectStreams)
+	}
+
+	args = append(args,
+		"-read_intervals", "%+"+strconv.Itoa(durationSec),
+		"-show_streams",
+		"-show_packets",
+		"-show_entries", "packet=stream_index,pts_time,dts_time,"+
+			"duration_time,size,flags:stream=index,codec_type,codec_name,"+
+			"profile,width,height,r_frame_rate,avg_frame_rate,bit_rate",
+		"-print_format", "json")
+	args = append(args, inputArgs(uri, transport)...)
+
+	cmd := command(ctx, ffprobePath, args...)
+
+	var errText bytes.Buffer
+
+	cmd.Stderr = &errText
+
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("get ffprobe stdout: %w", err)
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return nil, fmt.Errorf("start ffprobe: %w", err)
+	}
+
+	decodeErr := decodeArrays(out, map[string]func(*json.Decoder) error{
+		"packets": func(d *json.Decoder) error {
+			var pp probePacket
+
+			err := d.Decode(&pp)
+			if err != nil {
+				return err
+			}
+
+			p, err := pp.packet()
+			if err != nil {
+				return err
+			}
+
+			return handle(p)
+		},
+		"streams": func(d *json.Decoder) error {
+			var s Stream
+
+			err := d.Decode(&s)
+			if err != nil {
+				return err
+			}
+
+			streams = append(streams, s)
+
+			return nil
+		},
+	})
+	if decodeErr != nil {
+		drain(out)
+	}
+
+	err = cmd.Wait()
+	if err != nil {
+		return nil, runError(ctx, "run ffprobe", err, errText.String())
+	}
+
+	if decodeErr != nil {
+		return nil, fmt.Errorf("JSON decode packets: %w", decodeErr)
+	}
+
+	return streams, nil
+}
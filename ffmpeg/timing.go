@@ -0,0 +1,239 @@
+package ffmpeg
+
+import (
+	"context"
+	"math"
+	"sort"
+)
+
+// discontinuityFactor is how many nominal intervals timestamp should jump
+// to be counted as discontinuity.
+const discontinuityFactor = 3
+
+// droppedFrameFactor is how many nominal intervals should pass between
+// frames to count missing frames between them as dropped.
+const droppedFrameFactor = 1.5
+
+// StreamTiming is timestamps analysis of stream packets. DTS and PTS are
+// analyzed separately: DTS intervals are between consecutive packets in
+// decoding order, PTS intervals are between consecutive packets in
+// presentation order, so B-frames reordering isn't counted as jitter.
+// Nominal interval is median of intervals. Average interval and dropped
+// frames are measured by PTS.
+type StreamTiming struct {
+	Packets            int     `json:"packets"`
+	IntervalAvgMs      float64 `json:"interval_avg_ms"`
+	DTSJitterMs        float64 `json:"dts_jitter_ms"`
+	PTSJitterMs        float64 `json:"pts_jitter_ms"`
+	DTSDiscontinuities int     `json:"dts_discontinuities"`
+	PTSDiscontinuities int     `json:"pts_discontinuities"`
+	BackwardDTS        int     `json:"backward_dts"`
+	MissingDTS         int     `json:"missing_dts"`
+	MissingPTS         int     `json:"missing_pts"`
+	DroppedFrames      int     `json:"dropped_frames"`
+}
+
+// Timing is timestamps analysis of first video and audio streams. A/V sync
+// drift is change of audio to video PTS offset between sample start and
+// end.
+type Timing struct {
+	Video         *StreamTiming `json:"video,omitempty"`
+	Audio         *StreamTiming `json:"audio,omitempty"`
+	AVSyncDriftMs *float64      `json:"av_sync_drift_ms,omitempty"`
+}
+
+type streamTimestamps struct {
+	packets     int
+	missingDTS  int
+	missingPTS  int
+	backwardDTS int
+	dts         []float64
+	pts         []float64
+}
+
+func (st *streamTimestamps) add(p Packet) {
+	st.packets++
+
+	if p.DTSTime == nil {
+		st.missingDTS++
+	} else {
+		if len(st.dts) != 0 && *p.DTSTime < st.dts[len(st.dts)-1] {
+			st.backwardDTS++
+		}
+		st.dts = append(st.dts, *p.DTSTime)
+	}
+
+	if p.PTSTime == nil {
+		st.missingPTS++
+	} else {
+		st.pts = append(st.pts, *p.PTSTime)
+	}
+}
+
+// timestampIntervals is intervals between consecutive timestamps. Negative
+// intervals are skipped, they are counted as backward timestamps.
+type timestampIntervals []float64
+
+func newTimestampIntervals(ts []float64) (ti timestampIntervals) {
+	for i := 1; i < len(ts); i++ {
+		if d := ts[i] - ts[i-1]; d >= 0 {
+			ti = append(ti, d)
+		}
+	}
+	return
+}
+
+// stats returns average and standard deviation of intervals.
+func (ti timestampIntervals) stats() (avg, jitter float64) {
+	if len(ti) == 0 {
+		return 0, 0
+	}
+
+	var sum float64
+	for _, d := range ti {
+		sum += d
+	}
+	avg = sum / float64(len(ti))
+
+	var sqSum float64
+	for _, d := range ti {
+		sqSum += (d - avg) * (d - avg)
+	}
+
+	return avg, math.Sqrt(sqSum / float64(len(ti)))
+}
+
+func (ti timestampIntervals) nominal() float64 {
+	if len(ti) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), ti...)
+	sort.Float64s(sorted)
+	return sorted[len(sorted)/2]
+}
+
+func (ti timestampIntervals) discontinuities() (n int) {
+	nominal := ti.nominal()
+	if nominal == 0 {
+		return 0
+	}
+	for _, d := range ti {
+		if d > nominal*discontinuityFactor {
+			n++
+		}
+	}
+	return
+}
+
+func (ti timestampIntervals) droppedFrames() (n int) {
+	nominal := ti.nominal()
+	if nominal == 0 {
+		return 0
+	}
+	for _, d := range ti {
+		if d > nominal*droppedFrameFactor {
+			n += int(math.Round(d/nominal)) - 1
+		}
+	}
+	return
+}
+
+// presentationPTS returns PTS in presentation order.
+func (st *streamTimestamps) presentationPTS() []float64 {
+	pts := append([]float64(nil), st.pts...)
+	sort.Float64s(pts)
+	return pts
+}
+
+func (st *streamTimestamps) timing() *StreamTiming {
+	t := &StreamTiming{
+		Packets:     st.packets,
+		BackwardDTS: st.backwardDTS,
+		MissingDTS:  st.missingDTS,
+		MissingPTS:  st.missingPTS,
+	}
+
+	dts := newTimestampIntervals(st.dts)
+
+	_, dtsJitter := dts.stats()
+
+	t.DTSJitterMs = dtsJitter * 1000
+	t.DTSDiscontinuities = dts.discontinuities()
+
+	pts := newTimestampIntervals(st.presentationPTS())
+
+	ptsAvg, ptsJitter := pts.stats()
+
+	t.IntervalAvgMs = ptsAvg * 1000
+	t.PTSJitterMs = ptsJitter * 1000
+	t.PTSDiscontinuities = pts.discontinuities()
+	t.DroppedFrames = pts.droppedFrames()
+
+	return t
+}
+
+// bounds returns first and last PTS in presentation order.
+func (st *streamTimestamps) bounds() (first, last float64, ok bool) {
+	if len(st.pts) == 0 {
+		return 0, 0, false
+	}
+	first, last = st.pts[0], st.pts[0]
+	for _, ts := range st.pts {
+		first = math.Min(first, ts)
+		last = math.Max(last, ts)
+	}
+	return first, last, true
+}
+
+// AnalyzeTiming reads stream for given duration and analyzes its packets
+// timestamps.
+func AnalyzeTiming(ctx context.Context, ffprobePath, uri, transport string,
+	durationSec int) (t Timing, err error) {
+
+	sts := map[int]*streamTimestamps{}
+
+	streams, err := ProbePackets(ctx, ffprobePath, uri, transport,
+		durationSec, "", func(p Packet) error {
+			st, ok := sts[p.StreamIndex]
+			if !ok {
+				st = &streamTimestamps{}
+				sts[p.StreamIndex] = st
+			}
+			st.add(p)
+			return nil
+		})
+	if err != nil {
+		return
+	}
+
+	var video, audio *streamTimestamps
+
+	for _, s := range streams {
+		st, ok := sts[s.Index]
+		if !ok {
+			continue
+		}
+		switch {
+		case s.CodecType == "video" && video == nil:
+			video = st
+			t.Video = st.timing()
+		case s.CodecType == "audio" && audio == nil:
+			audio = st
+			t.Audio = st.timing()
+		}
+	}
+
+	if video == nil || audio == nil {
+		return
+	}
+
+	vFirst, vLast, vOk := video.bounds()
+	aFirst, aLast, aOk := audio.bounds()
+
+	if vOk && aOk {
+		drift := ((aLast - vLast) - (aFirst - vFirst)) * 1000
+		t.AVSyncDriftMs = &drift
+	}
+
+	return
+}
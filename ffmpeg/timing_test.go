@@ -0,0 +1,74 @@
+package ffmpeg
+
+import (
+	"testing"
+)
+
+func timedPacket(dts, pts float64) Packet {
+	return Packet{DTSTime: &dts, PTSTime: &pts}
+}
+
+func TestStreamTimingReordering(t *testing.T) {
+	var st streamTimestamps
+
+	// I P B B P B B with 40ms frame interval, PTS are reordered.
+	for _, p := range [][2]float64{
+		{0.00, 0.04}, {0.04, 0.16}, {0.08, 0.08}, {0.12, 0.12},
+		{0.16, 0.28}, {0.20, 0.20}, {0.24, 0.24},
+	} {
+		st.add(timedPacket(p[0], p[1]))
+	}
+
+	tm := st.timing()
+
+	if tm.PTSJitterMs > 1e-6 {
+		t.Errorf("expected zero PTS jitter, got %f", tm.PTSJitterMs)
+	}
+	if tm.DTSJitterMs > 1e-6 {
+		t.Errorf("expected zero DTS jitter, got %f", tm.DTSJitterMs)
+	}
+	if tm.PTSDiscontinuities != 0 || tm.DTSDiscontinuities != 0 {
+		t.Errorf("expected no discontinuities, got PTS %d, DTS %d",
+			tm.PTSDiscontinuities, tm.DTSDiscontinuities)
+	}
+	if tm.DroppedFrames != 0 {
+		t.Errorf("expected no dropped frames, got %d", tm.DroppedFrames)
+	}
+}
+
+func TestStreamTimingDiscontinuities(t *testing.T) {
+	var st streamTimestamps
+
+	// DTS are continuous while PTS jump by 1s after fourth packet.
+	for i := 0; i < 8; i++ {
+		ts := float64(i) * 0.04
+		pts := ts
+		if i >= 4 {
+			pts += 1
+		}
+		st.add(timedPacket(ts, pts))
+	}
+
+	var noPTS float64
+	st.add(Packet{DTSTime: &noPTS})
+
+	tm := st.timing()
+
+	if tm.DTSDiscontinuities != 0 {
+		t.Errorf("expected no DTS discontinuities, got %d",
+			tm.DTSDiscontinuities)
+	}
+	if tm.PTSDiscontinuities != 1 {
+		t.Errorf("expected 1 PTS discontinuity, got %d",
+			tm.PTSDiscontinuities)
+	}
+	if tm.PTSJitterMs == 0 {
+		t.Error("expected non-zero PTS jitter")
+	}
+	if tm.BackwardDTS != 1 {
+		t.Errorf("expected 1 backward DTS, got %d", tm.BackwardDTS)
+	}
+	if tm.MissingPTS != 1 {
+		t.Errorf("expected 1 missing PTS, got %d", tm.MissingPTS)
+	}
+}
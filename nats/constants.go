@@ -4,6 +4,7 @@ import "fmt"
 
 const (
 	taskResultsSubject = "task-results"
+	taskCancelsSubject = "task-cancels"
 )
 
 func tasksSubject(geoLocation string, taskType string) string {
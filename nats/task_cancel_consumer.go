@@ -0,0 +1,84 @@
+package nats
+
+import (
+	"encoding/json"
+	"fmt"
+
+	stan "github.com/nats-io/stan.go"
+	"github.com/sirupsen/logrus"
+)
+
+type TaskCanceller interface {
+	CancelTask(taskID string)
+}
+
+// TaskCancelConsumer receives task cancels. Unlike TaskConsumer it is not
+// subscribed to queue group, so every worker gets every cancel.
+type TaskCancelConsumer struct {
+	taskCanceller TaskCanceller
+	conn          stan.Conn
+	sub           stan.Subscription
+	log           *logrus.Entry
+}
+
+func NewTaskCancelConsumer(natsURL, clusterID, clientID string,
+	tc TaskCanceller) (tcc *TaskCancelConsumer, err error) {
+
+	tcc = &TaskCancelConsumer{
+		taskCanceller: tc,
+		log:           logrus.WithField("subsystem", "nats_task_cancel_consumer"),
+	}
+
+	var conn stan.Conn
+
+	conn, err = stan.Connect(clusterID, clientID+"-task-cancel-consumer",
+		stan.NatsURL(natsURL))
+	if err != nil {
+		err = fmt.Errorf("nats connect: %w", err)
+		return
+	}
+	defer func() {
+		if err != nil && tcc.conn != nil {
+			cerr := tcc.conn.Close()
+			if cerr != nil {
+				logrus.WithError(cerr).Error("failed to close connection")
+			}
+		}
+	}()
+
+	tcc.conn = conn
+
+	tcc.sub, err = tcc.conn.Subscribe(taskCancelsSubject, tcc.handleMsg)
+	if err != nil {
+		err = fmt.Errorf("subscribe to subject: %w", err)
+		return
+	}
+
+	return
+}
+
+func (tcc *TaskCancelConsumer) handleMsg(msg *stan.Msg) {
+	var taskID string
+
+	err := json.Unmarshal(msg.Data, &taskID)
+	if err != nil {
+		tcc.log.WithError(err).Error("failed to JSON unmarshal task ID")
+		return
+	}
+
+	tcc.taskCanceller.CancelTask(taskID)
+}
+
+func (tcc *TaskCancelConsumer) Close() error {
+	err := tcc.sub.Unsubscribe()
+	if err != nil {
+		return fmt.Errorf("unsubscribe: %w", err)
+	}
+
+	err = tcc.conn.Close()
+	if err != nil {
+		return fmt.Errorf("close connection: %w", err)
+	}
+
+	return nil
+}
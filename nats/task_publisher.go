@@ -33,3 +33,11 @@ func (tp *TaskPublisher) PublishTask(t entity.Task) error {
 	}
 	return tp.conn.Publish(tasksSubject(t.GeoLocation, t.Type), tJSON)
 }
+
+func (tp *TaskPublisher) PublishTaskCancel(taskID string) error {
+	tIDJSON, err := json.Marshal(taskID)
+	if err != nil {
+		return fmt.Errorf("JSON marshal task ID: %w", err)
+	}
+	return tp.conn.Publish(taskCancelsSubject, tIDJSON)
+}
@@ -1,13 +1,15 @@
 package pinger
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/sparrc/go-ping"
 
 	"github.com/dimuls/camtester/entity"
+	"github.com/dimuls/camtester/worker"
 )
 
 const TaskType = "ping"
@@ -25,92 +27,98 @@ type TaskResultPublisher interface {
 	PublishTaskResult(entity.TaskResult) error
 }
 
+// payload is ping task payload, it's camera host.
+type payload string
+
+func (p payload) Validate() error {
+	if p == "" {
+		return errors.New("host is empty")
+	}
+	return nil
+}
+
 type Pinger struct {
-	taskResultPublisher TaskResultPublisher
-	log                 *logrus.Entry
+	*worker.Base
 }
 
 func NewPinger(trp TaskResultPublisher) *Pinger {
 	return &Pinger{
-		taskResultPublisher: trp,
-		log:                 logrus.WithField("subsystem", "pinger"),
+		Base: worker.NewBase(trp, logrus.WithField("subsystem", "pinger")),
 	}
 }
 
 func (p *Pinger) HandleTask(t entity.Task) error {
-	log := p.log.WithField("task_id", t.ID)
-
-	log.Debug("task received")
-
-	var host string
+	var host payload
 
-	tr := entity.TaskResult{TaskID: t.ID}
-
-	err := t.UnmarshalPayload(&host)
-	if err != nil {
-		errMsg := "failed to unmarshal task payload"
-		log.WithError(err).WithField("payload", string(t.Payload)).
-			Error(errMsg)
-		return p.handleError(tr, errMsg, err)
+	wt, ok, err := p.StartTask(t, &host)
+	if !ok {
+		return err
 	}
+	defer wt.Done()
 
-	pg, err := ping.NewPinger(host)
+	ctx, log, tr := wt.Ctx, wt.Log, wt.Result
+
+	pg, err := ping.NewPinger(string(host))
 	if err != nil {
 		errMsg := "failed to create pinger"
 		log.WithError(err).WithField("host", host).Error(errMsg)
-		return p.handleError(tr, errMsg, err)
+		return p.PublishError(tr, entity.ErrorCodeHostUnreachable,
+			entity.ErrorStagePing, errMsg, err)
 	}
 
 	pg.Count = 100
 	pg.Interval = 100 * time.Millisecond
 	pg.Timeout = 11 * time.Second
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			pg.Stop()
+		case <-done:
+		}
+	}()
+
 	pg.Run()
+	close(done)
+
+	if errors.Is(ctx.Err(), context.Canceled) {
+		log.Info("task cancelled")
+		return nil
+	}
+
+	if ctx.Err() != nil {
+		errMsg := "failed to ping host"
+		log.WithError(ctx.Err()).Error(errMsg)
+		return p.PublishError(tr, entity.ErrorCodeTimeout,
+			entity.ErrorStagePing, errMsg, ctx.Err())
+	}
 
 	stats := pg.Statistics()
 
-	err = tr.MarshalPayload(PingResult{
+	pr := PingResult{
 		PacketsSent:     stats.PacketsSent,
 		PacketsReceived: stats.PacketsRecv,
 		MinRtt:          stats.MinRtt,
 		MaxRtt:          stats.MaxRtt,
 		AvgRtt:          stats.AvgRtt,
 		StdDevRtt:       stats.StdDevRtt,
-	})
-	if err != nil {
-		log.WithError(err).WithField("payload", stats.PacketLoss).
-			Error("failed to marshal task result payload")
-		return fmt.Errorf("marshal task result payload: %w", err)
 	}
 
-	tr.Ok = true
-	tr.Time = time.Now()
-
-	err = p.taskResultPublisher.PublishTaskResult(tr)
-	if err != nil {
-		log.WithError(err).Error("failed to publish task result")
-		return fmt.Errorf("publish task result: %w", err)
+	if pr.PacketsReceived == 0 {
+		te := entity.NewTaskError(entity.ErrorCodeHostUnreachable,
+			entity.ErrorStagePing, "no ping replies received")
+		te.Details = pr
+		log.WithField("host", host).Error(te.Message)
+		return p.PublishTaskError(tr, te)
 	}
 
-	log.Debug("task successfully handled")
-
-	return nil
-}
-func (p *Pinger) handleError(tr entity.TaskResult,
-	errMsg string, err error) error {
-
-	tr.Time = time.Now()
-
-	err = tr.MarshalPayload(errMsg + ": " + err.Error())
+	err = p.PublishResult(tr, pr)
 	if err != nil {
-		p.log.WithError(err).Error("failed to marshal task result payload")
-		return fmt.Errorf("marshal task result payload: %w", err)
+		return err
 	}
 
-	err = p.taskResultPublisher.PublishTaskResult(tr)
-	if err != nil {
-		p.log.WithError(err).Error("failed to publish task result")
-		return fmt.Errorf("publish task result: %w", err)
-	}
+	log.Debug("task successfully handled")
 
 	return nil
 }
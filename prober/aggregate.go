@@ -0,0 +1,119 @@
+package prober
+
+import (
+	"github.com/dimuls/camtester/ffmpeg"
+)
+
+// videoAggregator aggregates video frames as they are analyzed, so frames
+// are not kept in memory. Only temporal outliers and timestamps of frames
+// are kept for z-score peaks detection.
+type videoAggregator struct {
+	frames       int
+	blackFrames  int
+	freezeFrames int
+
+	isBlack  bool
+	isFreeze bool
+
+	firstTime float64
+	lastTime  float64
+
+	touts []float64
+	times []float64
+
+	black   segmentTracker
+	freeze  segmentTracker
+	quality qualityAccumulator
+}
+
+func (a *videoAggregator) add(f ffmpeg.VideoFrame) error {
+	if a.frames == 0 {
+		a.firstTime = f.Time
+	}
+	a.lastTime = f.Time
+
+	a.touts = append(a.touts, f.Tout)
+	a.times = append(a.times, f.Time)
+
+	if a.isBlack {
+		if f.BlackEnd != nil {
+			a.isBlack = false
+		} else {
+			a.blackFrames++
+		}
+	} else {
+		if f.BlackEnd != nil {
+			a.blackFrames += a.frames
+		}
+		if f.BlackStart != nil {
+			a.isBlack = true
+			a.blackFrames++
+		}
+	}
+
+	if a.isFreeze {
+		if f.FreezeEnd != nil {
+			a.isFreeze = false
+		} else {
+			a.freezeFrames++
+		}
+	} else {
+		if f.FreezeEnd != nil {
+			a.freezeFrames += a.frames
+		}
+		if f.FreezeStart != nil {
+			a.isFreeze = true
+			a.freezeFrames++
+		}
+	}
+
+	a.black.add(f.BlackStart, f.BlackEnd)
+	a.freeze.add(f.FreezeStart, f.FreezeEnd)
+	a.quality.add(f)
+
+	a.frames++
+
+	return nil
+}
+
+// audioAggregator aggregates audio frames as they are analyzed, so frames
+// are not kept in memory.
+type audioAggregator struct {
+	frames        int
+	silenceFrames int
+
+	isSilence bool
+
+	firstTime float64
+	lastTime  float64
+
+	silence segmentTracker
+	levels  levelsAccumulator
+}
+
+func (a *audioAggregator) add(f ffmpeg.AudioFrame) error {
+	if a.frames == 0 {
+		a.firstTime = f.Time
+	}
+	a.lastTime = f.Time
+
+	if a.isSilence {
+		if f.SilenceEnd != nil {
+			a.isSilence = false
+		} else {
+			a.silenceFrames++
+		}
+	} else {
+		if f.SilenceStart != nil {
+			a.isSilence = true
+			a.silenceFrames++
+		}
+	}
+
+	a.silence.add(f.SilenceStart, f.SilenceEnd)
+	a.levels.add(f)
+
+	a.frames++
+
+	return nil
+}
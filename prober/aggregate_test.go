@@ -0,0 +1,62 @@
+package prober
+
+import (
+	"testing"
+
+	"github.com/dimuls/camtester/ffmpeg"
+)
+
+// benchmarkFrames is count of aggregated frames, which is 10 minutes of
+// 25 fps video.
+const benchmarkFrames = 15000
+
+func BenchmarkVideoAggregator(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		var va videoAggregator
+		for j := 0; j < benchmarkFrames; j++ {
+			err := va.add(ffmpeg.VideoFrame{
+				Time:   float64(j) / 25,
+				Tout:   float64(j%100) / 1000,
+				YAvg:   110,
+				YMin:   16,
+				YMax:   235,
+				UAvg:   127,
+				VAvg:   129,
+				SatAvg: 20,
+				HueAvg: 150,
+				Blur:   3,
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+		va.quality.quality()
+	}
+}
+
+func BenchmarkAudioAggregator(b *testing.B) {
+	loudness := -23.0
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		var aa audioAggregator
+		for j := 0; j < benchmarkFrames; j++ {
+			err := aa.add(ffmpeg.AudioFrame{
+				Time:               float64(j) * 1024 / 48000,
+				RMSLevel:           -25,
+				PeakLevel:          -3,
+				PeakCount:          2,
+				DCOffset:           0.0001,
+				MomentaryLoudness:  &loudness,
+				IntegratedLoudness: &loudness,
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+		aa.levels.audioLevels()
+	}
+}
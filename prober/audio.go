@@ -0,0 +1,99 @@
+package prober
+
+import (
+	"math"
+
+	"github.com/dimuls/camtester/ffmpeg"
+)
+
+// Audio quality flags of probe result.
+const (
+	QualityFlagClipping = "clipping"
+	QualityFlagDCOffset = "dc_offset"
+)
+
+// clippingLevelDB is peak level in dBFS starting from which peak samples
+// are counted as clipped.
+const clippingLevelDB = -0.1
+
+// maxDCOffset is maximum absolute average DC offset of normal audio.
+const maxDCOffset = 0.01
+
+// AudioLevels is audio frames loudness and levels statistics. Levels which
+// can't be measured, for example of digital silence, are nil.
+type AudioLevels struct {
+	IntegratedLoudnessLUFS   *float64 `json:"integrated_loudness_lufs"`
+	MaxMomentaryLoudnessLUFS *float64 `json:"max_momentary_loudness_lufs"`
+	TruePeakDBTP             *float64 `json:"true_peak_dbtp"`
+	PeakLevelDBFS            *float64 `json:"peak_level_dbfs"`
+	ClippedSamples           int      `json:"clipped_samples"`
+	DCOffset                 float64  `json:"dc_offset"`
+}
+
+func finite(f float64) bool {
+	return !math.IsInf(f, 0) && !math.IsNaN(f)
+}
+
+// maxFinite returns max of current value and v if v is finite.
+func maxFinite(cur *float64, v float64) *float64 {
+	if !finite(v) || (cur != nil && *cur >= v) {
+		return cur
+	}
+	if cur == nil {
+		cur = new(float64)
+	}
+	*cur = v
+	return cur
+}
+
+// levelsAccumulator accumulates loudness and levels of audio frames.
+type levelsAccumulator struct {
+	levels   AudioLevels
+	dcOffset statsAccumulator
+}
+
+func (a *levelsAccumulator) add(f ffmpeg.AudioFrame) {
+	l := &a.levels
+
+	if f.IntegratedLoudness != nil && finite(*f.IntegratedLoudness) {
+		if l.IntegratedLoudnessLUFS == nil {
+			l.IntegratedLoudnessLUFS = new(float64)
+		}
+		*l.IntegratedLoudnessLUFS = *f.IntegratedLoudness
+	}
+
+	if f.MomentaryLoudness != nil {
+		l.MaxMomentaryLoudnessLUFS = maxFinite(l.MaxMomentaryLoudnessLUFS,
+			*f.MomentaryLoudness)
+	}
+
+	if f.TruePeak != nil {
+		l.TruePeakDBTP = maxFinite(l.TruePeakDBTP, *f.TruePeak)
+	}
+
+	l.PeakLevelDBFS = maxFinite(l.PeakLevelDBFS, f.PeakLevel)
+
+	if finite(f.PeakLevel) && f.PeakLevel >= clippingLevelDB {
+		l.ClippedSamples += int(f.PeakCount)
+	}
+
+	if finite(f.DCOffset) {
+		a.dcOffset.add(f.DCOffset)
+	}
+}
+
+func (a *levelsAccumulator) audioLevels() AudioLevels {
+	l := a.levels
+	l.DCOffset = a.dcOffset.stats.Avg
+	return l
+}
+
+func audioQualityFlags(l AudioLevels) (flags []string) {
+	if l.ClippedSamples > 0 {
+		flags = append(flags, QualityFlagClipping)
+	}
+	if math.Abs(l.DCOffset) > maxDCOffset {
+		flags = append(flags, QualityFlagDCOffset)
+	}
+	return
+}
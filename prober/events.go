@@ -0,0 +1,131 @@
+package prober
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	EventTypeBlack           = "black"
+	EventTypeFreeze          = "freeze"
+	EventTypeSilence         = "silence"
+	EventTypeTemporalOutlier = "temporal_outlier"
+)
+
+// Event is detected sample segment. Times are in seconds from sample
+// start. Segment which is not ended in sample ends at last frame.
+type Event struct {
+	Type        string  `json:"type"`
+	StartSec    float64 `json:"start_sec"`
+	EndSec      float64 `json:"end_sec"`
+	DurationSec float64 `json:"duration_sec"`
+}
+
+func newEvent(typ string, start, end, origin float64) Event {
+	return Event{
+		Type:        typ,
+		StartSec:    start - origin,
+		EndSec:      end - origin,
+		DurationSec: end - start,
+	}
+}
+
+// eventsOrigin returns timestamp of first frame of sample.
+func eventsOrigin(va *videoAggregator, aa *audioAggregator) float64 {
+	origin := math.Inf(1)
+	if va.frames != 0 {
+		origin = va.firstTime
+	}
+	if aa.frames != 0 && aa.firstTime < origin {
+		origin = aa.firstTime
+	}
+	if math.IsInf(origin, 1) {
+		return 0
+	}
+	return origin
+}
+
+// segment is detected segment with frame timestamps of start and end.
+type segment struct {
+	start, end float64
+}
+
+// segmentTracker tracks segments marked by start and end markers of frames
+// as frames arrive.
+type segmentTracker struct {
+	start    *float64
+	segments []segment
+}
+
+func (st *segmentTracker) add(start, end *float64) {
+	if end != nil && st.start != nil {
+		st.segments = append(st.segments, segment{start: *st.start, end: *end})
+		st.start = nil
+	}
+	if start != nil {
+		s := *start
+		st.start = &s
+	}
+}
+
+// events returns events of tracked segments. Segment which is not ended in
+// sample ends at last frame.
+func (st *segmentTracker) events(typ string, lastTime, origin float64) (
+	es []Event) {
+
+	for _, s := range st.segments {
+		es = append(es, newEvent(typ, s.start, s.end, origin))
+	}
+
+	if st.start != nil {
+		es = append(es, newEvent(typ, *st.start, lastTime, origin))
+	}
+
+	return
+}
+
+// probeEvents returns events of sample sorted by start time.
+func probeEvents(va *videoAggregator, aa *audioAggregator,
+	toutZScores []int) (es []Event) {
+
+	origin := eventsOrigin(va, aa)
+
+	if va.frames != 0 {
+		es = append(es, va.black.events(EventTypeBlack, va.lastTime,
+			origin)...)
+		es = append(es, va.freeze.events(EventTypeFreeze, va.lastTime,
+			origin)...)
+	}
+
+	if aa.frames != 0 {
+		es = append(es, aa.silence.events(EventTypeSilence, aa.lastTime,
+			origin)...)
+	}
+
+	peakStart := -1
+
+	for i, zs := range toutZScores {
+		if zs != 0 {
+			if peakStart == -1 {
+				peakStart = i
+			}
+			continue
+		}
+		if peakStart != -1 {
+			es = append(es, newEvent(EventTypeTemporalOutlier,
+				va.times[peakStart], va.times[i-1], origin))
+			peakStart = -1
+		}
+	}
+
+	if peakStart != -1 {
+		es = append(es, newEvent(EventTypeTemporalOutlier,
+			va.times[peakStart], va.times[len(toutZScores)-1], origin))
+	}
+
+	sort.SliceStable(es, func(i, j int) bool {
+		return es[i].StartSec < es[j].StartSec
+	})
+
+	return
+}
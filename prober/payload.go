@@ -0,0 +1,183 @@
+package prober
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/dimuls/camtester/ffmpeg"
+	"github.com/dimuls/camtester/worker"
+)
+
+const (
+	defaultSampleDurationSec = 10
+	maxSampleDurationSec     = 60
+
+	defaultZScoreLag       = 20
+	defaultZScoreThreshold = 10
+	defaultZScoreInfluence = 0.5
+)
+
+// Payload is probe task payload. It can be plain string stream URI too.
+// Zero options mean defaults.
+type Payload struct {
+	URI         string `json:"uri"`
+	DurationSec int    `json:"duration_sec,omitempty"`
+	Transport   string `json:"transport,omitempty"`
+
+	ZScore  ZScoreOptions  `json:"zscore,omitempty"`
+	Black   BlackOptions   `json:"black,omitempty"`
+	Freeze  FreezeOptions  `json:"freeze,omitempty"`
+	Silence SilenceOptions `json:"silence,omitempty"`
+	Quality QualityOptions `json:"quality,omitempty"`
+}
+
+type ZScoreOptions struct {
+	Lag       int     `json:"lag,omitempty"`
+	Threshold float64 `json:"threshold,omitempty"`
+	Influence float64 `json:"influence,omitempty"`
+}
+
+type BlackOptions struct {
+	MinDurationSec float64 `json:"min_duration_sec,omitempty"`
+	PixelThreshold float64 `json:"pixel_threshold,omitempty"`
+}
+
+type FreezeOptions struct {
+	NoiseDB        float64 `json:"noise_db,omitempty"`
+	MinDurationSec float64 `json:"min_duration_sec,omitempty"`
+}
+
+type SilenceOptions struct {
+	NoiseDB        float64 `json:"noise_db,omitempty"`
+	MinDurationSec float64 `json:"min_duration_sec,omitempty"`
+}
+
+func (p *Payload) UnmarshalJSON(data []byte) error {
+	var uri string
+
+	if json.Unmarshal(data, &uri) == nil {
+		*p = Payload{URI: uri}
+		return nil
+	}
+
+	type payload Payload
+
+	var pp payload
+
+	err := json.Unmarshal(data, &pp)
+	if err != nil {
+		return err
+	}
+
+	*p = Payload(pp)
+
+	return nil
+}
+
+func (p Payload) StreamURI() string {
+	return p.URI
+}
+
+func (p Payload) Validate() error {
+	if p.URI == "" {
+		return errors.New("uri is empty")
+	}
+
+	if p.DurationSec < 0 || p.DurationSec > maxSampleDurationSec {
+		return fmt.Errorf("duration_sec is not in [0, %d]",
+			maxSampleDurationSec)
+	}
+
+	err := worker.ValidateTransport(p.Transport)
+	if err != nil {
+		return err
+	}
+
+	if p.ZScore.Lag < 0 {
+		return errors.New("zscore lag is negative")
+	}
+
+	if p.ZScore.Threshold < 0 {
+		return errors.New("zscore threshold is negative")
+	}
+
+	if p.ZScore.Influence < 0 || p.ZScore.Influence > 1 {
+		return errors.New("zscore influence is not in [0, 1]")
+	}
+
+	if p.Black.MinDurationSec < 0 {
+		return errors.New("black min_duration_sec is negative")
+	}
+
+	if p.Black.PixelThreshold < 0 || p.Black.PixelThreshold > 1 {
+		return errors.New("black pixel_threshold is not in [0, 1]")
+	}
+
+	if p.Freeze.NoiseDB > 0 {
+		return errors.New("freeze noise_db is positive")
+	}
+
+	if p.Freeze.MinDurationSec < 0 {
+		return errors.New("freeze min_duration_sec is negative")
+	}
+
+	if p.Silence.NoiseDB > 0 {
+		return errors.New("silence noise_db is positive")
+	}
+
+	if p.Silence.MinDurationSec < 0 {
+		return errors.New("silence min_duration_sec is negative")
+	}
+
+	q := p.Quality
+
+	if q.MinBrightness < 0 || q.MaxBrightness < 0 || q.MinContrast < 0 ||
+		q.MaxBlur < 0 || q.MinSaturation < 0 || q.MaxColourCast < 0 {
+		return errors.New("quality threshold is negative")
+	}
+
+	if q.MaxBrightness != 0 && q.MinBrightness > q.MaxBrightness {
+		return errors.New(
+			"quality min_brightness is greater than max_brightness")
+	}
+
+	return nil
+}
+
+func (p Payload) sampleDurationSec() int {
+	if p.DurationSec == 0 {
+		return defaultSampleDurationSec
+	}
+	return p.DurationSec
+}
+
+func (p Payload) zScore() ZScoreOptions {
+	o := p.ZScore
+	if o.Lag == 0 {
+		o.Lag = defaultZScoreLag
+	}
+	if o.Threshold == 0 {
+		o.Threshold = defaultZScoreThreshold
+	}
+	if o.Influence == 0 {
+		o.Influence = defaultZScoreInfluence
+	}
+	return o
+}
+
+func (p Payload) videoOptions() ffmpeg.VideoOptions {
+	return ffmpeg.VideoOptions{
+		BlackMinDurationSec:  p.Black.MinDurationSec,
+		BlackPixelThreshold:  p.Black.PixelThreshold,
+		FreezeNoiseDB:        p.Freeze.NoiseDB,
+		FreezeMinDurationSec: p.Freeze.MinDurationSec,
+	}
+}
+
+func (p Payload) audioOptions() ffmpeg.AudioOptions {
+	return ffmpeg.AudioOptions{
+		SilenceNoiseDB:        p.Silence.NoiseDB,
+		SilenceMinDurationSec: p.Silence.MinDurationSec,
+	}
+}
@@ -1,26 +1,20 @@
 package prober
 
 import (
-	"encoding/base64"
+	"context"
 	"errors"
-	"fmt"
 	"math"
-	"os"
-	"path"
-	"time"
 
 	"github.com/gonum/stat"
 	"github.com/sirupsen/logrus"
 
 	"github.com/dimuls/camtester/entity"
 	"github.com/dimuls/camtester/ffmpeg"
+	"github.com/dimuls/camtester/worker"
 )
 
 const TaskType = "probe"
 
-const sampleContainerExt = "mkv"
-const sampleDurationSec = 10
-
 type ProbeResult struct {
 	SampleDurationSec     int `json:"sample_duration_sec"`
 	RecordingErrors       int `json:"recording_errors"`
@@ -30,6 +24,14 @@ type ProbeResult struct {
 	TemporalOutliersPeaks int `json:"temporal_outliers_peaks"`
 	AudioFrames           int `json:"audio_frames"`
 	SilenceFrames         int `json:"silence_frames"`
+
+	Events []Event `json:"events"`
+
+	// Quality and audio levels are empty if sample has no video or audio
+	// frames.
+	Quality      *Quality     `json:"quality,omitempty"`
+	AudioLevels  *AudioLevels `json:"audio_levels,omitempty"`
+	QualityFlags []string     `json:"quality_flags,omitempty"`
 }
 
 type RestreamerProvider interface {
@@ -41,144 +43,68 @@ type TaskResultPublisher interface {
 }
 
 type Prober struct {
-	ffmpegPath, ffprobePath string
-	restreamerProvider      RestreamerProvider
-	taskResultPublisher     TaskResultPublisher
-	log                     *logrus.Entry
+	ffmpegPath         string
+	restreamerProvider RestreamerProvider
+
+	*worker.Base
 }
 
 func NewProber(rp RestreamerProvider, trp TaskResultPublisher,
-	ffmpegPath, ffprobePath string) *Prober {
+	ffmpegPath string) *Prober {
 	return &Prober{
-		ffmpegPath:          ffmpegPath,
-		ffprobePath:         ffprobePath,
-		restreamerProvider:  rp,
-		taskResultPublisher: trp,
-		log:                 logrus.WithField("subsystem", "prober"),
+		ffmpegPath:         ffmpegPath,
+		restreamerProvider: rp,
+		Base:               worker.NewBase(trp, logrus.WithField("subsystem", "prober")),
 	}
 }
 
 func (p *Prober) HandleTask(t entity.Task) error {
-	log := p.log.WithField("task_id", t.ID)
-
-	log.Debug("task received")
+	var pl Payload
 
-	var uri string
-
-	tr := entity.TaskResult{TaskID: t.ID}
-
-	err := t.UnmarshalPayload(&uri)
-	if err != nil {
-		errMsg := "failed to unmarshal task payload"
-		log.WithError(err).WithField("payload", string(t.Payload)).
-			Error(errMsg)
-		return p.handleError(tr, errMsg, err)
+	wt, ok, err := p.StartStreamTask(t, &pl, p.restreamerProvider)
+	if !ok {
+		return err
 	}
+	defer wt.Done()
 
-	restreamerAddr, err := p.restreamerProvider.ProvideRestreamer(uri)
-	if err != nil {
-		errMsg := "failed to get restreamer host"
-		log.WithError(err).Error(errMsg)
-		return p.handleError(tr, errMsg, err)
-	}
-
-	log = log.WithField("restreamer_host", restreamerAddr)
-
-	log.Debug("got restreamer host")
-
-	origURIBase64 := base64.StdEncoding.EncodeToString([]byte(uri))
-
-	uri = fmt.Sprintf("rtsp://%s/%s", restreamerAddr, origURIBase64)
-
-	tempDir := os.TempDir()
-	tempFile := path.Join(tempDir, fmt.Sprintf("probe-%d.%s", t.ID,
-		sampleContainerExt))
-
-	recordingErrors, err := ffmpeg.RecordStream(p.ffmpegPath, uri, sampleDurationSec,
-		tempFile)
+	ctx, log, tr := wt.Ctx, wt.Log, wt.Result
 
-	defer func() {
-		err = os.Remove(tempFile)
-		if err != nil && !errors.Is(err, os.ErrNotExist) {
-			log.WithError(err).Error("failed to remove sample file")
-		}
-	}()
-
-	if err != nil {
-		errMsg := "failed to record"
-		log.WithError(err).Error(errMsg)
-		return p.handleError(tr, errMsg, err)
-	}
+	var (
+		va videoAggregator
+		aa audioAggregator
+	)
 
-	vfs, err := ffmpeg.ProbeVideo(p.ffprobePath, tempFile)
+	an, err := ffmpeg.AnalyzeStream(ctx, p.ffmpegPath, wt.StreamURI,
+		pl.Transport, pl.sampleDurationSec(), pl.videoOptions(),
+		pl.audioOptions(),
+		ffmpeg.FrameHandlers{Video: va.add, Audio: aa.add})
 	if err != nil {
-		errMsg := "failed to probe video"
+		if errors.Is(err, context.Canceled) {
+			log.Info("task cancelled")
+			return nil
+		}
+		errMsg := "failed to analyze stream"
 		log.WithError(err).Error(errMsg)
-		return p.handleError(tr, errMsg, err)
+		return p.PublishError(tr, ffmpeg.ErrorCode(err),
+			entity.ErrorStageProbe, errMsg, err)
 	}
 
-	afs, err := ffmpeg.ProbeAudio(p.ffprobePath, tempFile)
-	if err != nil {
-		errMsg := "failed to probe audio"
-		log.WithError(err).Error(errMsg)
-		return p.handleError(tr, errMsg, err)
+	pr := ProbeResult{
+		SampleDurationSec: pl.sampleDurationSec(),
+		RecordingErrors:   an.ErrorLines,
+		VideoFrames:       va.frames,
+		BlackFrames:       va.blackFrames,
+		FreezeFrames:      va.freezeFrames,
+		AudioFrames:       aa.frames,
+		SilenceFrames:     aa.silenceFrames,
 	}
 
-	var (
-		pr ProbeResult
-
-		touts    []float64
-		isBlack  bool
-		isFreeze bool
-	)
-
-	pr.SampleDurationSec = sampleDurationSec
-	pr.RecordingErrors = recordingErrors
-	pr.VideoFrames = len(vfs)
-	pr.AudioFrames = len(afs)
-
-	for i, f := range vfs {
-		touts = append(touts, f.Tout)
-
-		if isBlack {
-			if f.BlackEnd != nil {
-				isBlack = false
-			} else {
-				pr.BlackFrames++
-			}
-		} else {
-			if f.BlackEnd != nil {
-				pr.BlackFrames += i
-			}
-			if f.BlackStart != nil {
-				isBlack = true
-				pr.BlackFrames++
-			}
-		}
+	zo := pl.zScore()
 
-		if isFreeze {
-			if f.FreezeEnd != nil {
-				isFreeze = false
-			} else {
-				pr.FreezeFrames++
-			}
-		} else {
-			if f.FreezeEnd != nil {
-				pr.FreezeFrames += i
-			}
-			if f.FreezeStart != nil {
-				isFreeze = true
-				pr.FreezeFrames++
-			}
-		}
-
-	}
-
-	const lag = 20
 	var toutZScores []int
 
-	if len(touts) > lag {
-		toutZScores = zScore(touts, lag, 10, 0.5)
+	if len(va.touts) > zo.Lag {
+		toutZScores = zScore(va.touts, zo.Lag, zo.Threshold, zo.Influence)
 	}
 
 	var isPrevTop bool
@@ -194,39 +120,23 @@ func (p *Prober) HandleTask(t entity.Task) error {
 		}
 	}
 
-	var isSilence bool
+	pr.Events = probeEvents(&va, &aa, toutZScores)
 
-	for i, f := range afs {
-		if isSilence {
-			if f.SilenceEnd != nil {
-				if !isSilence {
-					pr.SilenceFrames += i
-				}
-				isSilence = false
-			} else {
-				pr.SilenceFrames++
-			}
-		} else {
-			if f.SilenceStart != nil {
-				isSilence = true
-				pr.SilenceFrames++
-			}
-		}
+	if va.frames != 0 {
+		q := va.quality.quality()
+		pr.Quality = &q
+		pr.QualityFlags = qualityFlags(q, pl.Quality)
 	}
 
-	tr.Ok = true
-	tr.Time = time.Now()
-
-	err = tr.MarshalPayload(pr)
-	if err != nil {
-		log.WithError(err).Error("failed to marshal task result payload")
-		return fmt.Errorf("marshal tast result payload: %w", err)
+	if aa.frames != 0 {
+		l := aa.levels.audioLevels()
+		pr.AudioLevels = &l
+		pr.QualityFlags = append(pr.QualityFlags, audioQualityFlags(l)...)
 	}
 
-	err = p.taskResultPublisher.PublishTaskResult(tr)
+	err = p.PublishResult(tr, pr)
 	if err != nil {
-		log.WithError(err).Error("failed to publish task result")
-		return fmt.Errorf("publish task result: %w", err)
+		return err
 	}
 
 	log.Debug("task successfully handled")
@@ -234,26 +144,6 @@ func (p *Prober) HandleTask(t entity.Task) error {
 	return nil
 }
 
-func (p *Prober) handleError(tr entity.TaskResult,
-	errMsg string, err error) error {
-
-	tr.Time = time.Now()
-
-	err = tr.MarshalPayload(errMsg + ": " + err.Error())
-	if err != nil {
-		p.log.WithError(err).Error("failed to marshal task result payload")
-		return fmt.Errorf("marshal task result payload: %w", err)
-	}
-
-	err = p.taskResultPublisher.PublishTaskResult(tr)
-	if err != nil {
-		p.log.WithError(err).Error("failed to publish task result")
-		return fmt.Errorf("publish task result: %w", err)
-	}
-
-	return nil
-}
-
 func zScore(samples []float64, lag int, threshold, influence float64) (signals []int) {
 	signals = make([]int, len(samples))
 	filteredY := make([]float64, len(samples))
@@ -0,0 +1,149 @@
+package prober
+
+import (
+	"math"
+
+	"github.com/dimuls/camtester/ffmpeg"
+)
+
+// Quality flags of probe result.
+const (
+	QualityFlagDark        = "dark"
+	QualityFlagOverexposed = "overexposed"
+	QualityFlagLowContrast = "low_contrast"
+	QualityFlagBlurred     = "blurred"
+	QualityFlagNoColour    = "no_colour"
+	QualityFlagColourCast  = "colour_cast"
+)
+
+// Default quality thresholds for 8 bit video.
+const (
+	defaultMinBrightness = 40
+	defaultMaxBrightness = 220
+	defaultMinContrast   = 50
+	defaultMaxBlur       = 8
+	defaultMinSaturation = 2
+	defaultMaxColourCast = 20
+)
+
+// neutralChroma is chroma value of grey pixel in 8 bit video.
+const neutralChroma = 128
+
+type Stats struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+	Avg float64 `json:"avg"`
+}
+
+type statsAccumulator struct {
+	stats Stats
+	count int
+}
+
+func (a *statsAccumulator) add(v float64) {
+	if a.count == 0 || v < a.stats.Min {
+		a.stats.Min = v
+	}
+	if a.count == 0 || v > a.stats.Max {
+		a.stats.Max = v
+	}
+	a.stats.Avg += (v - a.stats.Avg) / float64(a.count+1)
+	a.count++
+}
+
+// Quality is video frames quality statistics. Brightness is luma average,
+// contrast is luma range, colour cast is distance of average chroma from
+// grey.
+type Quality struct {
+	Brightness Stats `json:"brightness"`
+	Contrast   Stats `json:"contrast"`
+	Saturation Stats `json:"saturation"`
+	Hue        Stats `json:"hue"`
+	ColourCast Stats `json:"colour_cast"`
+	Blur       Stats `json:"blur"`
+}
+
+// qualityAccumulator accumulates quality statistics of video frames.
+type qualityAccumulator struct {
+	brightness, contrast, saturation, hue, colourCast,
+	blur statsAccumulator
+}
+
+func (a *qualityAccumulator) add(f ffmpeg.VideoFrame) {
+	a.brightness.add(f.YAvg)
+	a.contrast.add(f.YMax - f.YMin)
+	a.saturation.add(f.SatAvg)
+	a.hue.add(f.HueAvg)
+	a.colourCast.add(math.Hypot(f.UAvg-neutralChroma, f.VAvg-neutralChroma))
+	a.blur.add(f.Blur)
+}
+
+func (a *qualityAccumulator) quality() Quality {
+	return Quality{
+		Brightness: a.brightness.stats,
+		Contrast:   a.contrast.stats,
+		Saturation: a.saturation.stats,
+		Hue:        a.hue.stats,
+		ColourCast: a.colourCast.stats,
+		Blur:       a.blur.stats,
+	}
+}
+
+// QualityOptions are thresholds of quality flags. Zero fields mean
+// defaults.
+type QualityOptions struct {
+	MinBrightness float64 `json:"min_brightness,omitempty"`
+	MaxBrightness float64 `json:"max_brightness,omitempty"`
+	MinContrast   float64 `json:"min_contrast,omitempty"`
+	MaxBlur       float64 `json:"max_blur,omitempty"`
+	MinSaturation float64 `json:"min_saturation,omitempty"`
+	MaxColourCast float64 `json:"max_colour_cast,omitempty"`
+}
+
+func (o QualityOptions) withDefaults() QualityOptions {
+	if o.MinBrightness == 0 {
+		o.MinBrightness = defaultMinBrightness
+	}
+	if o.MaxBrightness == 0 {
+		o.MaxBrightness = defaultMaxBrightness
+	}
+	if o.MinContrast == 0 {
+		o.MinContrast = defaultMinContrast
+	}
+	if o.MaxBlur == 0 {
+		o.MaxBlur = defaultMaxBlur
+	}
+	if o.MinSaturation == 0 {
+		o.MinSaturation = defaultMinSaturation
+	}
+	if o.MaxColourCast == 0 {
+		o.MaxColourCast = defaultMaxColourCast
+	}
+	return o
+}
+
+// qualityFlags returns flags of quality averages which are out of range.
+func qualityFlags(q Quality, o QualityOptions) (flags []string) {
+	o = o.withDefaults()
+
+	if q.Brightness.Avg < o.MinBrightness {
+		flags = append(flags, QualityFlagDark)
+	}
+	if q.Brightness.Avg > o.MaxBrightness {
+		flags = append(flags, QualityFlagOverexposed)
+	}
+	if q.Contrast.Avg < o.MinContrast {
+		flags = append(flags, QualityFlagLowContrast)
+	}
+	if q.Blur.Avg > o.MaxBlur {
+		flags = append(flags, QualityFlagBlurred)
+	}
+	if q.Saturation.Avg < o.MinSaturation {
+		flags = append(flags, QualityFlagNoColour)
+	}
+	if q.ColourCast.Avg > o.MaxColourCast {
+		flags = append(flags, QualityFlagColourCast)
+	}
+
+	return
+}
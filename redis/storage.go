@@ -2,7 +2,11 @@ package redis
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/dimuls/camtester/entity"
 	"github.com/mediocregopher/radix"
@@ -10,6 +14,54 @@ import (
 
 const taskResultTTL = 24 * 60 * 60
 
+// updateTaskScript sets task if stored task version equals to expected.
+// Returns -1 if task is not found, 0 on version conflict and 1 on success.
+var updateTaskScript = radix.NewEvalScript(1, `
+local cur = redis.call("GET", KEYS[1])
+if not cur then
+	return -1
+end
+local version = cjson.decode(cur).version or 0
+if version ~= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[2], "EX", ARGV[3])
+return 1
+`)
+
+// Task indexes are sorted sets of task IDs scored by task creation time in
+// milliseconds. Every index is split into shards by task ID, so indexes are
+// spread over cluster slots, and shards are merged on query.
+const (
+	tasksIndexKeyPref = "tasks-index:"
+	tasksIndexShards  = 16
+	tasksQueryBatch   = 100
+	tasksQueryLimit   = 1000
+)
+
+const (
+	attemptDeadlinesKey = "attempt-deadlines"
+	pendingAttemptsKey  = "pending-attempts"
+)
+
+const (
+	camerasKey          = "cameras"
+	cameraReferencesKey = "camera-references"
+)
+
+const (
+	historyKeyPref = "history:"
+	historyTTL     = 90 * 24 * time.Hour
+)
+
+const callbackDeliveriesKeyPref = "callback-deliveries:"
+
+const (
+	schedulesKey        = "schedules"
+	scheduleLockKeyPref = "schedule-lock:"
+	scheduleLockTTL     = 24 * 60 * 60
+)
+
 type Storage struct {
 	cluster *radix.Cluster
 }
@@ -61,5 +113,632 @@ func (s *Storage) SetTask(t entity.Task) error {
 		return fmt.Errorf("redis set: %w", err)
 	}
 
+	err = s.indexTask(t)
+	if err != nil {
+		return fmt.Errorf("index task: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateTask stores task with incremented version if stored task is not
+// updated since task is got.
+func (s *Storage) UpdateTask(t entity.Task) error {
+	expectedVersion := t.Version
+	t.Version++
+
+	tJSON, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("JSON marshal task: %w", err)
+	}
+
+	var res int
+
+	err = s.cluster.Do(updateTaskScript.FlatCmd(&res, []string{t.ID},
+		expectedVersion, string(tJSON), taskResultTTL))
+	if err != nil {
+		return fmt.Errorf("redis eval: %w", err)
+	}
+
+	switch res {
+	case -1:
+		return entity.ErrTaskNotFound
+	case 0:
+		return entity.ErrTaskVersionConflict
+	}
+
+	return nil
+}
+
+func tasksIndexKeys(t entity.Task) []string {
+	keys := []string{
+		tasksIndexKey("", "", ""),
+		tasksIndexKey(t.Type, "", ""),
+		tasksIndexKey("", t.GeoLocation, ""),
+		tasksIndexKey(t.Type, t.GeoLocation, ""),
+	}
+	for _, uri := range t.PayloadURIs() {
+		keys = append(keys, tasksIndexKey("", "", uri))
+	}
+
+	shard := tasksIndexShard(t.ID)
+	for i := range keys {
+		keys[i] = tasksIndexShardKey(keys[i], shard)
+	}
+
+	return keys
+}
+
+// tasksIndexKey returns most specific index key for given filters.
+func tasksIndexKey(taskType, geoLocation, payloadURI string) string {
+	switch {
+	case payloadURI != "":
+		return tasksIndexKeyPref + "payload:" + payloadURI
+	case taskType != "" && geoLocation != "":
+		return tasksIndexKeyPref + "geo-type:" + geoLocation + ":" + taskType
+	case taskType != "":
+		return tasksIndexKeyPref + "type:" + taskType
+	case geoLocation != "":
+		return tasksIndexKeyPref + "geo:" + geoLocation
+	default:
+		return tasksIndexKeyPref + "all"
+	}
+}
+
+func tasksIndexShardKey(key string, shard int) string {
+	return key + ":" + strconv.Itoa(shard)
+}
+
+// indexTask adds task to its indexes. Shards of different indexes are in
+// different cluster slots, so every index is updated by its own pipeline.
+func (s *Storage) indexTask(t entity.Task) error {
+	score := t.CreatedAt.UnixNano() / int64(time.Millisecond)
+	expired := time.Now().Add(-taskResultTTL*time.Second).UnixNano() /
+		int64(time.Millisecond)
+
+	for _, key := range tasksIndexKeys(t) {
+		err := s.cluster.Do(radix.Pipeline(
+			radix.FlatCmd(nil, "ZADD", key, "NX", score, t.ID),
+			radix.FlatCmd(nil, "ZREMRANGEBYSCORE", key, "-inf", expired)))
+		if err != nil {
+			return fmt.Errorf("redis pipeline: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) tasksIndexRange(key, max, min string, skip, count int) (
+	es []indexEntry, err error) {
+
+	var idsScores []string
+
+	err = s.cluster.Do(radix.Cmd(&idsScores, "ZREVRANGEBYSCORE", key,
+		max, min, "WITHSCORES", "LIMIT", strconv.Itoa(skip),
+		strconv.Itoa(count)))
+	if err != nil {
+		return nil, fmt.Errorf("redis zrevrangebyscore: %w", err)
+	}
+
+	for i := 0; i+1 < len(idsScores); i += 2 {
+		e := indexEntry{id: idsScores[i]}
+
+		e.score, err = strconv.ParseInt(idsScores[i+1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse task score: %w", err)
+		}
+
+		es = append(es, e)
+	}
+
+	return es, nil
+}
+
+// Tasks returns tasks matching query from newest to oldest. Shards of most
+// specific index are merged and remaining filters are applied to loaded
+// tasks. Cursor is score and ID of last scanned task.
+func (s *Storage) Tasks(q entity.TaskQuery) (ts []entity.Task,
+	nextCursor string, err error) {
+
+	if q.Limit <= 0 || q.Limit > tasksQueryLimit {
+		q.Limit = tasksQueryLimit
+	}
+
+	key := tasksIndexKey(q.Type, q.GeoLocation, q.PayloadURI)
+
+	max := "+inf"
+	min := "-inf"
+
+	if !q.To.IsZero() {
+		max = strconv.FormatInt(q.To.UnixNano()/int64(time.Millisecond), 10)
+	}
+	if !q.From.IsZero() {
+		min = strconv.FormatInt(q.From.UnixNano()/int64(time.Millisecond), 10)
+	}
+
+	var after *indexEntry
+
+	if q.Cursor != "" {
+		var c indexEntry
+
+		c, err = parseTasksCursor(q.Cursor)
+		if err != nil {
+			err = fmt.Errorf("%w: %s", entity.ErrInvalidCursor, err)
+			return
+		}
+
+		after = &c
+	}
+
+	var m mergedIter
+
+	for shard := 0; shard < tasksIndexShards; shard++ {
+		m = append(m, newShardIter(tasksIndexShardKey(key, shard),
+			s.tasksIndexRange, max, min, after))
+	}
+
+	for {
+		var (
+			e  indexEntry
+			it *shardIter
+			ok bool
+		)
+
+		e, it, ok, err = m.next()
+		if err != nil || !ok {
+			return
+		}
+
+		var t entity.Task
+
+		t, err = s.Task(e.id)
+		if err != nil {
+			if errors.Is(err, entity.ErrTaskNotFound) {
+				err = s.cluster.Do(radix.Cmd(nil, "ZREM", it.key, e.id))
+				if err != nil {
+					err = fmt.Errorf("redis zrem: %w", err)
+					return
+				}
+				it.removed()
+				continue
+			}
+			return
+		}
+
+		if !q.Match(t) {
+			continue
+		}
+
+		ts = append(ts, t)
+
+		if len(ts) == q.Limit {
+			nextCursor = e.cursor()
+			return
+		}
+	}
+}
+
+func (s *Storage) Schedules() (ss []entity.Schedule, err error) {
+	var sJSONs map[string]string
+
+	err = s.cluster.Do(radix.Cmd(&sJSONs, "HGETALL", schedulesKey))
+	if err != nil {
+		err = fmt.Errorf("redis hgetall: %w", err)
+		return
+	}
+
+	for _, sJSON := range sJSONs {
+		var sc entity.Schedule
+
+		err = json.Unmarshal([]byte(sJSON), &sc)
+		if err != nil {
+			err = fmt.Errorf("JSON unmarshal schedule: %w", err)
+			return
+		}
+
+		ss = append(ss, sc)
+	}
+
+	return
+}
+
+func (s *Storage) Schedule(scheduleID string) (sc entity.Schedule, err error) {
+	var sJSON string
+
+	err = s.cluster.Do(radix.Cmd(&sJSON, "HGET", schedulesKey, scheduleID))
+	if err != nil {
+		err = fmt.Errorf("redis hget: %w", err)
+		return
+	}
+
+	if sJSON == "" {
+		err = entity.ErrScheduleNotFound
+		return
+	}
+
+	err = json.Unmarshal([]byte(sJSON), &sc)
+	if err != nil {
+		err = fmt.Errorf("JSON unmarshal schedule: %w", err)
+		return
+	}
+
+	return
+}
+
+func (s *Storage) SetSchedule(sc entity.Schedule) error {
+	sJSON, err := json.Marshal(sc)
+	if err != nil {
+		return fmt.Errorf("JSON marshal schedule: %w", err)
+	}
+
+	err = s.cluster.Do(radix.Cmd(nil, "HSET", schedulesKey, sc.ID,
+		string(sJSON)))
+	if err != nil {
+		return fmt.Errorf("redis hset: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteSchedule(scheduleID string) error {
+	var deleted int
+
+	err := s.cluster.Do(radix.Cmd(&deleted, "HDEL", schedulesKey, scheduleID))
+	if err != nil {
+		return fmt.Errorf("redis hdel: %w", err)
+	}
+
+	if deleted == 0 {
+		return entity.ErrScheduleNotFound
+	}
+
+	return nil
+}
+
+// LockSchedule returns true if schedule fire at given time is not locked
+// yet and locks it, so only one caller fires it.
+func (s *Storage) LockSchedule(scheduleID string, fireTime time.Time) (
+	bool, error) {
+
+	var res string
+
+	err := s.cluster.Do(radix.FlatCmd(&res, "SET",
+		scheduleLockKeyPref+scheduleID+":"+
+			strconv.FormatInt(fireTime.Unix(), 10),
+		1, "NX", "EX", scheduleLockTTL))
+	if err != nil {
+		return false, fmt.Errorf("redis set: %w", err)
+	}
+
+	return res == "OK", nil
+}
+
+func (s *Storage) AddCallbackDelivery(taskID string,
+	d entity.CallbackDelivery) error {
+
+	dJSON, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("JSON marshal callback delivery: %w", err)
+	}
+
+	key := callbackDeliveriesKeyPref + taskID
+
+	err = s.cluster.Do(radix.Cmd(nil, "RPUSH", key, string(dJSON)))
+	if err != nil {
+		return fmt.Errorf("redis rpush: %w", err)
+	}
+
+	err = s.cluster.Do(radix.FlatCmd(nil, "EXPIRE", key, taskResultTTL))
+	if err != nil {
+		return fmt.Errorf("redis expire: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) CallbackDeliveries(taskID string) (
+	ds []entity.CallbackDelivery, err error) {
+
+	var dJSONs []string
+
+	err = s.cluster.Do(radix.Cmd(&dJSONs, "LRANGE",
+		callbackDeliveriesKeyPref+taskID, "0", "-1"))
+	if err != nil {
+		err = fmt.Errorf("redis lrange: %w", err)
+		return
+	}
+
+	for _, dJSON := range dJSONs {
+		var d entity.CallbackDelivery
+
+		err = json.Unmarshal([]byte(dJSON), &d)
+		if err != nil {
+			err = fmt.Errorf("JSON unmarshal callback delivery: %w", err)
+			return
+		}
+
+		ds = append(ds, d)
+	}
+
+	return
+}
+
+func (s *Storage) Cameras() (cs []entity.Camera, err error) {
+	var cJSONs map[string]string
+
+	err = s.cluster.Do(radix.Cmd(&cJSONs, "HGETALL", camerasKey))
+	if err != nil {
+		err = fmt.Errorf("redis hgetall: %w", err)
+		return
+	}
+
+	for _, cJSON := range cJSONs {
+		var c entity.Camera
+
+		err = json.Unmarshal([]byte(cJSON), &c)
+		if err != nil {
+			err = fmt.Errorf("JSON unmarshal camera: %w", err)
+			return
+		}
+
+		cs = append(cs, c)
+	}
+
+	return
+}
+
+func (s *Storage) Camera(cameraID string) (c entity.Camera, err error) {
+	var cJSON string
+
+	err = s.cluster.Do(radix.Cmd(&cJSON, "HGET", camerasKey, cameraID))
+	if err != nil {
+		err = fmt.Errorf("redis hget: %w", err)
+		return
+	}
+
+	if cJSON == "" {
+		err = entity.ErrCameraNotFound
+		return
+	}
+
+	err = json.Unmarshal([]byte(cJSON), &c)
+	if err != nil {
+		err = fmt.Errorf("JSON unmarshal camera: %w", err)
+		return
+	}
+
+	return
+}
+
+func (s *Storage) SetCamera(c entity.Camera) error {
+	cJSON, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("JSON marshal camera: %w", err)
+	}
+
+	err = s.cluster.Do(radix.Cmd(nil, "HSET", camerasKey, c.ID,
+		string(cJSON)))
+	if err != nil {
+		return fmt.Errorf("redis hset: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteCamera(cameraID string) error {
+	var deleted int
+
+	err := s.cluster.Do(radix.Cmd(&deleted, "HDEL", camerasKey, cameraID))
+	if err != nil {
+		return fmt.Errorf("redis hdel: %w", err)
+	}
+
+	if deleted == 0 {
+		return entity.ErrCameraNotFound
+	}
+
+	err = s.cluster.Do(radix.Cmd(nil, "HDEL", cameraReferencesKey, cameraID))
+	if err != nil {
+		return fmt.Errorf("redis hdel reference: %w", err)
+	}
+
+	return nil
+}
+
+// CameraReference returns artifact ID of camera reference image.
+func (s *Storage) CameraReference(cameraID string) (string, error) {
+	var artifactID string
+
+	err := s.cluster.Do(radix.Cmd(&artifactID, "HGET", cameraReferencesKey,
+		cameraID))
+	if err != nil {
+		return "", fmt.Errorf("redis hget: %w", err)
+	}
+
+	if artifactID == "" {
+		return "", entity.ErrCameraReferenceNotFound
+	}
+
+	return artifactID, nil
+}
+
+func (s *Storage) SetCameraReference(cameraID, artifactID string) error {
+	err := s.cluster.Do(radix.Cmd(nil, "HSET", cameraReferencesKey, cameraID,
+		artifactID))
+	if err != nil {
+		return fmt.Errorf("redis hset: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) AddHistoryRecord(key string, r entity.HistoryRecord) error {
+	rJSON, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("JSON marshal history record: %w", err)
+	}
+
+	key = historyKeyPref + key
+	expired := time.Now().Add(-historyTTL).UnixNano() / int64(time.Millisecond)
+
+	err = s.cluster.Do(radix.FlatCmd(nil, "ZADD", key,
+		r.Time.UnixNano()/int64(time.Millisecond), string(rJSON)))
+	if err != nil {
+		return fmt.Errorf("redis zadd: %w", err)
+	}
+
+	err = s.cluster.Do(radix.FlatCmd(nil, "ZREMRANGEBYSCORE", key,
+		"-inf", expired))
+	if err != nil {
+		return fmt.Errorf("redis zremrangebyscore: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) History(key string, from, to time.Time) (
+	rs []entity.HistoryRecord, err error) {
+
+	var rJSONs []string
+
+	err = s.cluster.Do(radix.FlatCmd(&rJSONs, "ZRANGEBYSCORE",
+		historyKeyPref+key,
+		from.UnixNano()/int64(time.Millisecond),
+		to.UnixNano()/int64(time.Millisecond)))
+	if err != nil {
+		err = fmt.Errorf("redis zrangebyscore: %w", err)
+		return
+	}
+
+	for _, rJSON := range rJSONs {
+		var r entity.HistoryRecord
+
+		err = json.Unmarshal([]byte(rJSON), &r)
+		if err != nil {
+			err = fmt.Errorf("JSON unmarshal history record: %w", err)
+			return
+		}
+
+		rs = append(rs, r)
+	}
+
+	return
+}
+
+func attemptMember(taskID, attemptID string) string {
+	return taskID + ":" + attemptID
+}
+
+// addAttempt adds attempt scored by time in milliseconds to sorted set.
+func (s *Storage) addAttempt(key, taskID, attemptID string,
+	t time.Time) error {
+
+	err := s.cluster.Do(radix.FlatCmd(nil, "ZADD", key,
+		t.UnixNano()/int64(time.Millisecond),
+		attemptMember(taskID, attemptID)))
+	if err != nil {
+		return fmt.Errorf("redis zadd: %w", err)
+	}
+	return nil
+}
+
+// removeAttempt returns true if attempt is removed from sorted set by this
+// call, so concurrent callers can use it to claim attempt handling.
+func (s *Storage) removeAttempt(key, taskID, attemptID string) (
+	bool, error) {
+
+	var removed int
+
+	err := s.cluster.Do(radix.Cmd(&removed, "ZREM", key,
+		attemptMember(taskID, attemptID)))
+	if err != nil {
+		return false, fmt.Errorf("redis zrem: %w", err)
+	}
+
+	return removed == 1, nil
+}
+
+// attemptsBefore calls add for every attempt of sorted set which time is
+// not after given time.
+func (s *Storage) attemptsBefore(key string, now time.Time,
+	add func(taskID, attemptID string, t time.Time)) error {
+
+	var membersScores []string
+
+	err := s.cluster.Do(radix.FlatCmd(&membersScores, "ZRANGEBYSCORE",
+		key, "-inf", now.UnixNano()/int64(time.Millisecond),
+		"WITHSCORES"))
+	if err != nil {
+		return fmt.Errorf("redis zrangebyscore: %w", err)
+	}
+
+	for i := 0; i+1 < len(membersScores); i += 2 {
+		parts := strings.SplitN(membersScores[i], ":", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid attempt member %s", membersScores[i])
+		}
+
+		ms, err := strconv.ParseInt(membersScores[i+1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("parse attempt score: %w", err)
+		}
+
+		add(parts[0], parts[1], time.Unix(0, ms*int64(time.Millisecond)))
+	}
+
 	return nil
 }
+
+func (s *Storage) AddAttemptDeadline(d entity.AttemptDeadline) error {
+	return s.addAttempt(attemptDeadlinesKey, d.TaskID, d.AttemptID,
+		d.Deadline)
+}
+
+// RemoveAttemptDeadline returns true if deadline is removed by this call,
+// so concurrent callers can use it to claim deadline handling.
+func (s *Storage) RemoveAttemptDeadline(d entity.AttemptDeadline) (
+	bool, error) {
+	return s.removeAttempt(attemptDeadlinesKey, d.TaskID, d.AttemptID)
+}
+
+func (s *Storage) ExpiredAttemptDeadlines(now time.Time) (
+	ds []entity.AttemptDeadline, err error) {
+
+	err = s.attemptsBefore(attemptDeadlinesKey, now,
+		func(taskID, attemptID string, t time.Time) {
+			ds = append(ds, entity.AttemptDeadline{
+				TaskID:    taskID,
+				AttemptID: attemptID,
+				Deadline:  t,
+			})
+		})
+
+	return
+}
+
+func (s *Storage) AddPendingAttempt(pa entity.PendingAttempt) error {
+	return s.addAttempt(pendingAttemptsKey, pa.TaskID, pa.AttemptID,
+		pa.PublishAt)
+}
+
+// RemovePendingAttempt returns true if pending attempt is removed by this
+// call, so concurrent callers can use it to claim attempt publishing.
+func (s *Storage) RemovePendingAttempt(pa entity.PendingAttempt) (
+	bool, error) {
+	return s.removeAttempt(pendingAttemptsKey, pa.TaskID, pa.AttemptID)
+}
+
+func (s *Storage) DuePendingAttempts(now time.Time) (
+	pas []entity.PendingAttempt, err error) {
+
+	err = s.attemptsBefore(pendingAttemptsKey, now,
+		func(taskID, attemptID string, t time.Time) {
+			pas = append(pas, entity.PendingAttempt{
+				TaskID:    taskID,
+				AttemptID: attemptID,
+				PublishAt: t,
+			})
+		})
+
+	return
+}
@@ -0,0 +1,181 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// tasksIndexShard returns shard of task indexes where task is indexed.
+func tasksIndexShard(taskID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(taskID))
+	return int(h.Sum32() % tasksIndexShards)
+}
+
+// indexEntry is task ID with its score in task index.
+type indexEntry struct {
+	id    string
+	score int64
+}
+
+// before returns true if entry e goes before entry o in tasks query order:
+// from newest to oldest, entries with equal score are ordered by ID
+// descending like ZREVRANGEBYSCORE orders them.
+func (e indexEntry) before(o indexEntry) bool {
+	if e.score != o.score {
+		return e.score > o.score
+	}
+	return e.id > o.id
+}
+
+func (e indexEntry) cursor() string {
+	return fmt.Sprintf("%d:%s", e.score, e.id)
+}
+
+func parseTasksCursor(cursor string) (e indexEntry, err error) {
+	parts := strings.SplitN(cursor, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		err = errors.New("invalid cursor format")
+		return
+	}
+
+	e.score, err = strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		err = fmt.Errorf("parse cursor score: %w", err)
+		return
+	}
+
+	e.id = parts[1]
+
+	return
+}
+
+// indexRangeFunc returns up to count entries of index key like
+// ZREVRANGEBYSCORE key max min LIMIT skip count.
+type indexRangeFunc func(key, max, min string, skip, count int) (
+	[]indexEntry, error)
+
+// shardIter iterates over entries of task index shard in query order.
+// Entries are fetched by batches, next batch starts after consumed entries:
+// from score of last consumed entry skipping consumed entries with that
+// score.
+type shardIter struct {
+	key     string
+	rangeFn indexRangeFunc
+	max     string
+	min     string
+	skip    int
+	after   *indexEntry
+	buf     []indexEntry
+	done    bool
+}
+
+// newShardIter returns iterator of index shard entries with scores from max
+// to min. If after is not nil, entries which are not after it are skipped.
+func newShardIter(key string, rangeFn indexRangeFunc, max, min string,
+	after *indexEntry) *shardIter {
+
+	if after != nil {
+		max = strconv.FormatInt(after.score, 10)
+	}
+
+	return &shardIter{
+		key:     key,
+		rangeFn: rangeFn,
+		max:     max,
+		min:     min,
+		after:   after,
+	}
+}
+
+func (it *shardIter) consume(e indexEntry) {
+	score := strconv.FormatInt(e.score, 10)
+	if score != it.max {
+		it.max = score
+		it.skip = 0
+	}
+	it.skip++
+}
+
+func (it *shardIter) fetch() error {
+	es, err := it.rangeFn(it.key, it.max, it.min, it.skip, tasksQueryBatch)
+	if err != nil {
+		return err
+	}
+
+	it.done = len(es) < tasksQueryBatch
+
+	for _, e := range es {
+		if it.after != nil && !it.after.before(e) {
+			it.consume(e)
+			continue
+		}
+		it.buf = append(it.buf, e)
+	}
+
+	return nil
+}
+
+// peek returns next entry without consuming it. Ok is false if there are no
+// entries left.
+func (it *shardIter) peek() (e indexEntry, ok bool, err error) {
+	for len(it.buf) == 0 {
+		if it.done {
+			return e, false, nil
+		}
+		err = it.fetch()
+		if err != nil {
+			return
+		}
+	}
+	return it.buf[0], true, nil
+}
+
+// pop consumes entry returned by peek.
+func (it *shardIter) pop() {
+	it.consume(it.buf[0])
+	it.buf = it.buf[1:]
+}
+
+// removed must be called when entry consumed last is removed from index.
+func (it *shardIter) removed() {
+	it.skip--
+}
+
+// mergedIter iterates over entries of all shards of task index in query
+// order.
+type mergedIter []*shardIter
+
+// next consumes and returns next entry and its shard iterator. Ok is false
+// if there are no entries left.
+func (m mergedIter) next() (e indexEntry, it *shardIter, ok bool,
+	err error) {
+
+	for _, si := range m {
+		var (
+			se  indexEntry
+			sok bool
+		)
+
+		se, sok, err = si.peek()
+		if err != nil {
+			err = fmt.Errorf("shard %s: %w", si.key, err)
+			return
+		}
+
+		if sok && (it == nil || se.before(e)) {
+			e, it = se, si
+		}
+	}
+
+	if it == nil {
+		return
+	}
+
+	it.pop()
+
+	return e, it, true, nil
+}
@@ -0,0 +1,158 @@
+package redis
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// memoryIndex is in-memory task index shards which are ranged like
+// ZREVRANGEBYSCORE does.
+type memoryIndex map[string][]indexEntry
+
+func (mi memoryIndex) add(key string, e indexEntry) {
+	es := append(mi[key], e)
+	sort.Slice(es, func(i, j int) bool { return es[i].before(es[j]) })
+	mi[key] = es
+}
+
+func (mi memoryIndex) remove(key string, id string) {
+	es := mi[key]
+	for i, e := range es {
+		if e.id == id {
+			mi[key] = append(es[:i:i], es[i+1:]...)
+			return
+		}
+	}
+}
+
+func parseScoreBound(b string) int64 {
+	switch b {
+	case "+inf":
+		return 1<<63 - 1
+	case "-inf":
+		return -1 << 63
+	}
+	s, err := strconv.ParseInt(b, 10, 64)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func (mi memoryIndex) rangeFn(key, max, min string, skip, count int) (
+	[]indexEntry, error) {
+
+	maxScore, minScore := parseScoreBound(max), parseScoreBound(min)
+
+	var es []indexEntry
+
+	for _, e := range mi[key] {
+		if e.score > maxScore || e.score < minScore {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		es = append(es, e)
+		if len(es) == count {
+			break
+		}
+	}
+
+	return es, nil
+}
+
+func (mi memoryIndex) iter(after *indexEntry) mergedIter {
+	var m mergedIter
+	for shard := 0; shard < tasksIndexShards; shard++ {
+		m = append(m, newShardIter(tasksIndexShardKey("index", shard),
+			mi.rangeFn, "+inf", "-inf", after))
+	}
+	return m
+}
+
+func TestMergedIter(t *testing.T) {
+	mi := memoryIndex{}
+
+	var expected []indexEntry
+
+	// Shards have more entries with equal score than fetched by one batch.
+	for i := 0; i < 5*tasksQueryBatch*tasksIndexShards/2; i++ {
+		e := indexEntry{
+			id:    fmt.Sprintf("task-%05d", i),
+			score: int64(i / (2 * tasksQueryBatch * tasksIndexShards)),
+		}
+		mi.add(tasksIndexShardKey("index", tasksIndexShard(e.id)), e)
+		expected = append(expected, e)
+	}
+
+	sort.Slice(expected, func(i, j int) bool {
+		return expected[i].before(expected[j])
+	})
+
+	const pageSize = 3 * tasksQueryBatch * tasksIndexShards / 2
+
+	var (
+		got   []indexEntry
+		after *indexEntry
+	)
+
+	// Every page is scanned by new iterator started after cursor of
+	// previous page. Every 10th entry is removed from index when scanned.
+	for {
+		m := mi.iter(after)
+
+		n := 0
+
+		for n < pageSize {
+			e, it, ok, err := m.next()
+			if err != nil {
+				t.Fatalf("failed to get next entry: %v", err)
+			}
+			if !ok {
+				break
+			}
+
+			got = append(got, e)
+			n++
+
+			if len(got)%10 == 0 {
+				mi.remove(it.key, e.id)
+				it.removed()
+			}
+
+			c, err := parseTasksCursor(e.cursor())
+			if err != nil {
+				t.Fatalf("failed to parse cursor: %v", err)
+			}
+			after = &c
+		}
+
+		if n < pageSize {
+			break
+		}
+	}
+
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d entries, got %d", len(expected), len(got))
+	}
+
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("entry #%d: expected %v, got %v", i, expected[i],
+				got[i])
+		}
+	}
+}
+
+func TestParseTasksCursor(t *testing.T) {
+	for _, c := range []string{"", "1", "1:", "a:id"} {
+		_, err := parseTasksCursor(c)
+		if err == nil {
+			t.Errorf("cursor %q: expected error", c)
+		}
+	}
+}
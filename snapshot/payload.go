@@ -0,0 +1,39 @@
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dimuls/camtester/worker"
+)
+
+const maxWidth = 3840
+
+// Payload is snapshot task payload. Snapshot is scaled to width keeping
+// aspect ratio, zero width keeps frame size.
+type Payload struct {
+	URI       string `json:"uri"`
+	Width     int    `json:"width,omitempty"`
+	Transport string `json:"transport,omitempty"`
+}
+
+func (p Payload) StreamURI() string {
+	return p.URI
+}
+
+func (p Payload) Validate() error {
+	if p.URI == "" {
+		return errors.New("uri is empty")
+	}
+
+	if p.Width < 0 || p.Width > maxWidth {
+		return fmt.Errorf("width is not in [0, %d]", maxWidth)
+	}
+
+	err := worker.ValidateTransport(p.Transport)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
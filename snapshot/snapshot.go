@@ -0,0 +1,113 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/dimuls/camtester/entity"
+	"github.com/dimuls/camtester/ffmpeg"
+	"github.com/dimuls/camtester/worker"
+)
+
+const TaskType = "snapshot"
+
+const snapshotContentType = "image/jpeg"
+
+type SnapshotResult struct {
+	ArtifactID  string `json:"artifact_id"`
+	ContentType string `json:"content_type"`
+	Size        int    `json:"size"`
+}
+
+type RestreamerProvider interface {
+	ProvideRestreamer(uri string) (string, error)
+}
+
+type TaskResultPublisher interface {
+	PublishTaskResult(entity.TaskResult) error
+}
+
+type ArtifactStore interface {
+	SetArtifact(entity.Artifact) error
+}
+
+type Snapshotter struct {
+	ffmpegPath         string
+	artifactURLPref    string
+	restreamerProvider RestreamerProvider
+	artifactStore      ArtifactStore
+
+	*worker.Base
+}
+
+// NewSnapshotter creates snapshotter. Artifact URL of snapshot is artifact
+// URL prefix followed by artifact ID.
+func NewSnapshotter(rp RestreamerProvider, trp TaskResultPublisher,
+	as ArtifactStore, ffmpegPath, artifactURLPref string) *Snapshotter {
+	return &Snapshotter{
+		ffmpegPath:         ffmpegPath,
+		artifactURLPref:    artifactURLPref,
+		restreamerProvider: rp,
+		artifactStore:      as,
+		Base:               worker.NewBase(trp, logrus.WithField("subsystem", "snapshotter")),
+	}
+}
+
+func (s *Snapshotter) HandleTask(t entity.Task) error {
+	var p Payload
+
+	wt, ok, err := s.StartStreamTask(t, &p, s.restreamerProvider)
+	if !ok {
+		return err
+	}
+	defer wt.Done()
+
+	ctx, log, tr := wt.Ctx, wt.Log, wt.Result
+
+	image, err := ffmpeg.GrabFrame(ctx, s.ffmpegPath, wt.StreamURI,
+		p.Transport, p.Width)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			log.Info("task cancelled")
+			return nil
+		}
+		errMsg := "failed to grab frame"
+		log.WithError(err).Error(errMsg)
+		return s.PublishError(tr, ffmpeg.ErrorCode(err),
+			entity.ErrorStageGrab, errMsg, err)
+	}
+
+	a := entity.Artifact{
+		ID:          uuid.New().String(),
+		ContentType: snapshotContentType,
+		Data:        image,
+	}
+
+	err = s.artifactStore.SetArtifact(a)
+	if err != nil {
+		errMsg := "failed to store snapshot"
+		log.WithError(err).Error(errMsg)
+		return s.PublishError(tr, entity.ErrorCodeInternal,
+			entity.ErrorStageStore, errMsg, err)
+	}
+
+	log.WithField("artifact_id", a.ID).Debug("snapshot stored")
+
+	tr.ArtifactURL = s.artifactURLPref + a.ID
+
+	err = s.PublishResult(tr, SnapshotResult{
+		ArtifactID:  a.ID,
+		ContentType: a.ContentType,
+		Size:        len(a.Data),
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Debug("task successfully handled")
+
+	return nil
+}
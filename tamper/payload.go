@@ -0,0 +1,52 @@
+package tamper
+
+import (
+	"errors"
+
+	"github.com/dimuls/camtester/worker"
+)
+
+const defaultSSIMThreshold = 0.5
+
+// Payload is tamper task payload. Grabbed frame is compared with reference
+// image artifact or, if reference capture is requested, stored as new
+// reference.
+type Payload struct {
+	URI              string  `json:"uri"`
+	ReferenceID      string  `json:"reference_id,omitempty"`
+	CaptureReference bool    `json:"capture_reference,omitempty"`
+	SSIMThreshold    float64 `json:"ssim_threshold,omitempty"`
+	Transport        string  `json:"transport,omitempty"`
+}
+
+func (p Payload) StreamURI() string {
+	return p.URI
+}
+
+func (p Payload) Validate() error {
+	if p.URI == "" {
+		return errors.New("uri is empty")
+	}
+
+	if !p.CaptureReference && p.ReferenceID == "" {
+		return errors.New("reference_id is empty")
+	}
+
+	if p.SSIMThreshold < 0 || p.SSIMThreshold > 1 {
+		return errors.New("ssim_threshold is not in [0, 1]")
+	}
+
+	err := worker.ValidateTransport(p.Transport)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (p Payload) ssimThreshold() float64 {
+	if p.SSIMThreshold == 0 {
+		return defaultSSIMThreshold
+	}
+	return p.SSIMThreshold
+}
@@ -0,0 +1,148 @@
+package tamper
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/dimuls/camtester/entity"
+	"github.com/dimuls/camtester/ffmpeg"
+	"github.com/dimuls/camtester/worker"
+)
+
+const TaskType = "tamper"
+
+// referenceWidth is width of grabbed reference images and frames.
+const referenceWidth = 640
+
+// referenceContentType is content type of captured reference images.
+const referenceContentType = "image/jpeg"
+
+// TamperResult is result of tamper task. Only captured reference artifact ID
+// is set if reference capture is requested.
+type TamperResult struct {
+	*ffmpeg.Similarity
+	Tampered            bool   `json:"tampered"`
+	CapturedReferenceID string `json:"captured_reference_id,omitempty"`
+}
+
+type RestreamerProvider interface {
+	ProvideRestreamer(uri string) (string, error)
+}
+
+type TaskResultPublisher interface {
+	PublishTaskResult(entity.TaskResult) error
+}
+
+type ArtifactStore interface {
+	Artifact(artifactID string) (entity.Artifact, error)
+	SetArtifact(entity.Artifact) error
+}
+
+type Detector struct {
+	ffmpegPath         string
+	restreamerProvider RestreamerProvider
+	artifactStore      ArtifactStore
+
+	*worker.Base
+}
+
+func NewDetector(rp RestreamerProvider, trp TaskResultPublisher,
+	as ArtifactStore, ffmpegPath string) *Detector {
+	return &Detector{
+		ffmpegPath:         ffmpegPath,
+		restreamerProvider: rp,
+		artifactStore:      as,
+		Base:               worker.NewBase(trp, logrus.WithField("subsystem", "tamper")),
+	}
+}
+
+func (d *Detector) HandleTask(t entity.Task) error {
+	var p Payload
+
+	wt, ok, err := d.StartStreamTask(t, &p, d.restreamerProvider)
+	if !ok {
+		return err
+	}
+	defer wt.Done()
+
+	ctx, log, tr := wt.Ctx, wt.Log, wt.Result
+
+	var ref entity.Artifact
+
+	if !p.CaptureReference {
+		ref, err = d.artifactStore.Artifact(p.ReferenceID)
+		if err != nil {
+			errMsg := "failed to get reference"
+			log.WithError(err).WithField("reference_id", p.ReferenceID).
+				Error(errMsg)
+			code := entity.ErrorCodeInternal
+			if errors.Is(err, entity.ErrArtifactNotFound) {
+				code = entity.ErrorCodeInvalidPayload
+			}
+			return d.PublishError(tr, code, entity.ErrorStageStore, errMsg,
+				err)
+		}
+	}
+
+	frame, err := ffmpeg.GrabFrame(ctx, d.ffmpegPath, wt.StreamURI,
+		p.Transport, referenceWidth)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			log.Info("task cancelled")
+			return nil
+		}
+		errMsg := "failed to grab frame"
+		log.WithError(err).Error(errMsg)
+		return d.PublishError(tr, ffmpeg.ErrorCode(err),
+			entity.ErrorStageGrab, errMsg, err)
+	}
+
+	var res TamperResult
+
+	if p.CaptureReference {
+		a := entity.Artifact{
+			ID:          uuid.New().String(),
+			ContentType: referenceContentType,
+			Data:        frame,
+		}
+
+		err = d.artifactStore.SetArtifact(a)
+		if err != nil {
+			errMsg := "failed to store reference"
+			log.WithError(err).Error(errMsg)
+			return d.PublishError(tr, entity.ErrorCodeInternal,
+				entity.ErrorStageStore, errMsg, err)
+		}
+
+		log.WithField("artifact_id", a.ID).Debug("reference stored")
+
+		res.CapturedReferenceID = a.ID
+	} else {
+		s, err := ffmpeg.CompareImages(ctx, d.ffmpegPath, frame, ref.Data)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				log.Info("task cancelled")
+				return nil
+			}
+			errMsg := "failed to compare frame with reference"
+			log.WithError(err).Error(errMsg)
+			return d.PublishError(tr, ffmpeg.ErrorCode(err),
+				entity.ErrorStageCompare, errMsg, err)
+		}
+
+		res.Similarity = &s
+		res.Tampered = s.SSIM < p.ssimThreshold()
+	}
+
+	err = d.PublishResult(tr, res)
+	if err != nil {
+		return err
+	}
+
+	log.Debug("task successfully handled")
+
+	return nil
+}
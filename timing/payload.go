@@ -0,0 +1,49 @@
+package timing
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dimuls/camtester/worker"
+)
+
+const (
+	defaultSampleDurationSec = 20
+	maxSampleDurationSec     = 60
+)
+
+// Payload is timing task payload.
+type Payload struct {
+	URI         string `json:"uri"`
+	DurationSec int    `json:"duration_sec,omitempty"`
+	Transport   string `json:"transport,omitempty"`
+}
+
+func (p Payload) StreamURI() string {
+	return p.URI
+}
+
+func (p Payload) Validate() error {
+	if p.URI == "" {
+		return errors.New("uri is empty")
+	}
+
+	if p.DurationSec < 0 || p.DurationSec > maxSampleDurationSec {
+		return fmt.Errorf("duration_sec is not in [0, %d]",
+			maxSampleDurationSec)
+	}
+
+	err := worker.ValidateTransport(p.Transport)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (p Payload) sampleDurationSec() int {
+	if p.DurationSec == 0 {
+		return defaultSampleDurationSec
+	}
+	return p.DurationSec
+}
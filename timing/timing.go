@@ -0,0 +1,80 @@
+package timing
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/dimuls/camtester/entity"
+	"github.com/dimuls/camtester/ffmpeg"
+	"github.com/dimuls/camtester/worker"
+)
+
+const TaskType = "timing"
+
+type TimingResult struct {
+	SampleDurationSec int `json:"sample_duration_sec"`
+	ffmpeg.Timing
+}
+
+type RestreamerProvider interface {
+	ProvideRestreamer(uri string) (string, error)
+}
+
+type TaskResultPublisher interface {
+	PublishTaskResult(entity.TaskResult) error
+}
+
+type Analyzer struct {
+	ffprobePath        string
+	restreamerProvider RestreamerProvider
+
+	*worker.Base
+}
+
+func NewAnalyzer(rp RestreamerProvider, trp TaskResultPublisher,
+	ffprobePath string) *Analyzer {
+	return &Analyzer{
+		ffprobePath:        ffprobePath,
+		restreamerProvider: rp,
+		Base:               worker.NewBase(trp, logrus.WithField("subsystem", "timing")),
+	}
+}
+
+func (a *Analyzer) HandleTask(t entity.Task) error {
+	var p Payload
+
+	wt, ok, err := a.StartStreamTask(t, &p, a.restreamerProvider)
+	if !ok {
+		return err
+	}
+	defer wt.Done()
+
+	ctx, log, tr := wt.Ctx, wt.Log, wt.Result
+
+	timing, err := ffmpeg.AnalyzeTiming(ctx, a.ffprobePath, wt.StreamURI,
+		p.Transport, p.sampleDurationSec())
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			log.Info("task cancelled")
+			return nil
+		}
+		errMsg := "failed to analyze timing"
+		log.WithError(err).Error(errMsg)
+		return a.PublishError(tr, ffmpeg.ErrorCode(err),
+			entity.ErrorStageProbe, errMsg, err)
+	}
+
+	err = a.PublishResult(tr, TimingResult{
+		SampleDurationSec: p.sampleDurationSec(),
+		Timing:            timing,
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Debug("task successfully handled")
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package worker
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/dimuls/camtester/entity"
+)
+
+type RestreamerProvider interface {
+	ProvideRestreamer(uri string) (string, error)
+}
+
+// Payload is task payload which is validated after unmarshal.
+type Payload interface {
+	Validate() error
+}
+
+// StreamPayload is payload of task which reads camera stream.
+type StreamPayload interface {
+	Payload
+	StreamURI() string
+}
+
+// Task is task which handling is started by StartTask or StartStreamTask.
+// Done must be called when task handling is finished.
+type Task struct {
+	Ctx    context.Context
+	Result entity.TaskResult
+	Log    *logrus.Entry
+
+	// StreamURI is URI of stream restreamed by restreamer, it's set by
+	// StartStreamTask.
+	StreamURI string
+
+	cancel context.CancelFunc
+}
+
+func (t Task) Done() {
+	t.cancel()
+}
+
+// StartTask is common beginning of task handling: it creates task context,
+// skips cancelled task, unmarshals payload into p and validates it. If ok
+// is false, task is not handled and err should be returned by task
+// handler.
+func (b *Base) StartTask(t entity.Task, p Payload) (wt Task, ok bool,
+	err error) {
+
+	log := b.log.WithField("task_id", t.ID)
+
+	log.Debug("task received")
+
+	ctx, cancel := b.TaskContext(t)
+
+	wt = Task{
+		Ctx:    ctx,
+		Result: t.NewResult(),
+		Log:    log,
+		cancel: cancel,
+	}
+
+	defer func() {
+		if !ok {
+			cancel()
+		}
+	}()
+
+	if b.TaskCancelled(t.ID) {
+		log.Info("task is cancelled, skipping")
+		return wt, false, nil
+	}
+
+	err = t.UnmarshalPayload(p)
+	if err != nil {
+		errMsg := "failed to unmarshal task payload"
+		log.WithError(err).WithField("payload", string(t.Payload)).
+			Error(errMsg)
+		return wt, false, b.PublishError(wt.Result,
+			entity.ErrorCodeInvalidPayload, entity.ErrorStagePayload, errMsg,
+			err)
+	}
+
+	err = p.Validate()
+	if err != nil {
+		errMsg := "invalid task payload"
+		log.WithError(err).WithField("payload", string(t.Payload)).
+			Error(errMsg)
+		return wt, false, b.PublishError(wt.Result,
+			entity.ErrorCodeInvalidPayload, entity.ErrorStagePayload, errMsg,
+			err)
+	}
+
+	return wt, true, nil
+}
+
+// StartStreamTask starts task like StartTask and gets restreamer of
+// payload stream.
+func (b *Base) StartStreamTask(t entity.Task, p StreamPayload,
+	rp RestreamerProvider) (wt Task, ok bool, err error) {
+
+	wt, ok, err = b.StartTask(t, p)
+	if !ok {
+		return
+	}
+
+	restreamerAddr, err := rp.ProvideRestreamer(p.StreamURI())
+	if err != nil {
+		wt.Done()
+		errMsg := "failed to get restreamer host"
+		wt.Log.WithError(err).Error(errMsg)
+		return wt, false, b.PublishError(wt.Result,
+			entity.ErrorCodeRestreamerUnavailable,
+			entity.ErrorStageRestreamer, errMsg, err)
+	}
+
+	wt.Log = wt.Log.WithField("restreamer_host", restreamerAddr)
+
+	wt.Log.Debug("got restreamer host")
+
+	wt.StreamURI = RestreamerURI(restreamerAddr, p.StreamURI())
+
+	return wt, true, nil
+}
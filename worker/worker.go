@@ -0,0 +1,187 @@
+package worker
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/dimuls/camtester/entity"
+)
+
+type TaskResultPublisher interface {
+	PublishTaskResult(entity.TaskResult) error
+}
+
+// cancelledTaskTTL is how long cancelled task IDs are kept. Task which is
+// received later than this after its cancel is handled.
+const cancelledTaskTTL = time.Hour
+
+// Base is common part of task workers. It keeps cancel functions of running
+// tasks, IDs of cancelled tasks and publishes task results. Workers embed
+// it, so it implements task cancel handler.
+type Base struct {
+	taskResultPublisher TaskResultPublisher
+	log                 *logrus.Entry
+
+	cancels   map[string]map[context.Context]context.CancelFunc
+	cancelled map[string]time.Time
+	cancelsMx sync.Mutex
+}
+
+func NewBase(trp TaskResultPublisher, log *logrus.Entry) *Base {
+	return &Base{
+		taskResultPublisher: trp,
+		log:                 log,
+		cancels:             map[string]map[context.Context]context.CancelFunc{},
+		cancelled:           map[string]time.Time{},
+	}
+}
+
+// CancelTask cancels running task and records its ID, so task which is
+// still queued is skipped when received.
+func (b *Base) CancelTask(taskID string) {
+	b.cancelsMx.Lock()
+	defer b.cancelsMx.Unlock()
+
+	now := time.Now()
+
+	for id, at := range b.cancelled {
+		if now.Sub(at) > cancelledTaskTTL {
+			delete(b.cancelled, id)
+		}
+	}
+
+	b.cancelled[taskID] = now
+
+	cancels, ok := b.cancels[taskID]
+	if !ok {
+		return
+	}
+
+	b.log.WithField("task_id", taskID).Info("cancelling task")
+
+	for _, cancel := range cancels {
+		cancel()
+	}
+}
+
+// TaskContext returns context of task which is done when task is cancelled
+// or its deadline is exceeded.
+func (b *Base) TaskContext(t entity.Task) (context.Context,
+	context.CancelFunc) {
+
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+
+	if t.Deadline == nil {
+		ctx, cancel = context.WithCancel(context.Background())
+	} else {
+		ctx, cancel = context.WithDeadline(context.Background(), *t.Deadline)
+	}
+
+	b.cancelsMx.Lock()
+	if b.cancels[t.ID] == nil {
+		b.cancels[t.ID] = map[context.Context]context.CancelFunc{}
+	}
+	b.cancels[t.ID][ctx] = cancel
+	b.cancelsMx.Unlock()
+
+	return ctx, func() {
+		b.cancelsMx.Lock()
+		delete(b.cancels[t.ID], ctx)
+		if len(b.cancels[t.ID]) == 0 {
+			delete(b.cancels, t.ID)
+		}
+		b.cancelsMx.Unlock()
+		cancel()
+	}
+}
+
+// TaskCancelled returns true if task cancel is received during last
+// cancelledTaskTTL. It should be checked after TaskContext call, so cancel
+// received between them cancels task context.
+func (b *Base) TaskCancelled(taskID string) bool {
+	b.cancelsMx.Lock()
+	defer b.cancelsMx.Unlock()
+
+	at, ok := b.cancelled[taskID]
+
+	return ok && time.Since(at) <= cancelledTaskTTL
+}
+
+// PublishResult publishes successful task result with given payload.
+func (b *Base) PublishResult(tr entity.TaskResult,
+	payload interface{}) error {
+
+	tr.Ok = true
+	tr.Time = time.Now()
+
+	err := tr.MarshalPayload(payload)
+	if err != nil {
+		b.log.WithError(err).WithField("task_id", tr.TaskID).
+			Error("failed to marshal task result payload")
+		return fmt.Errorf("marshal task result payload: %w", err)
+	}
+
+	return b.publish(tr)
+}
+
+// PublishError publishes failed task result with task error of given code
+// and stage.
+func (b *Base) PublishError(tr entity.TaskResult,
+	code, stage, errMsg string, err error) error {
+
+	return b.PublishTaskError(tr, entity.NewTaskError(code, stage,
+		errMsg+": "+err.Error()))
+}
+
+// PublishTaskError publishes failed task result with given task error.
+func (b *Base) PublishTaskError(tr entity.TaskResult,
+	te entity.TaskError) error {
+
+	tr.Time = time.Now()
+	tr.Reason = te.Code
+
+	err := tr.MarshalPayload(te)
+	if err != nil {
+		b.log.WithError(err).WithField("task_id", tr.TaskID).
+			Error("failed to marshal task result payload")
+		return fmt.Errorf("marshal task result payload: %w", err)
+	}
+
+	return b.publish(tr)
+}
+
+func (b *Base) publish(tr entity.TaskResult) error {
+	err := b.taskResultPublisher.PublishTaskResult(tr)
+	if err != nil {
+		b.log.WithError(err).WithField("task_id", tr.TaskID).
+			Error("failed to publish task result")
+		return fmt.Errorf("publish task result: %w", err)
+	}
+	return nil
+}
+
+// ValidateTransport checks RTSP transport of restreamer stream: tcp, udp or
+// empty for ffmpeg default.
+func ValidateTransport(transport string) error {
+	switch transport {
+	case "", "tcp", "udp":
+		return nil
+	}
+	return errors.New("unknown transport")
+}
+
+// RestreamerURI returns RTSP URI of stream restreamed by restreamer of
+// given address.
+func RestreamerURI(restreamerAddr, uri string) string {
+	return fmt.Sprintf("rtsp://%s/%s", restreamerAddr,
+		base64.StdEncoding.EncodeToString([]byte(uri)))
+}